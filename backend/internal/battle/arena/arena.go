@@ -7,8 +7,23 @@ import (
 	"strings"
 )
 
+// Terrain is the kind of ground a tile is made of.
+type Terrain string
+
+const (
+	TerrainGrass Terrain = "grass"
+	TerrainWater Terrain = "water"
+	TerrainWall  Terrain = "wall"
+)
+
+// Walkable reports whether troops can stand on or move through the terrain.
+func (t Terrain) Walkable() bool {
+	return t != TerrainWater && t != TerrainWall
+}
+
 type Tile struct {
-	Troops []troops.Entity
+	Troops  []troops.Entity
+	Terrain Terrain
 }
 
 type Map struct {
@@ -22,7 +37,8 @@ func NewMap(width, height int) *Map {
 		tiles[y] = make([]*Tile, width)
 		for x := range tiles[y] {
 			tiles[y][x] = &Tile{
-				Troops: []troops.Entity{}, // Tiles are empty to start
+				Troops:  []troops.Entity{}, // Tiles are empty to start
+				Terrain: TerrainGrass,
 			}
 		}
 	}
@@ -124,7 +140,7 @@ func (m *Map) FindNearestEnemyBFS(t troops.Entity) (troops.Entity, []common.Posi
 		// Enqueue neighbors
 		for _, dir := range directions {
 			next := common.NewPosition(x+int(dir.X), y+int(dir.Y))
-			if !m.InBounds(next) || visited[next] {
+			if !m.InBounds(next) || visited[next] || !m.Walkable(next) {
 				continue
 			}
 			visited[next] = true
@@ -147,3 +163,8 @@ func (m *Map) InBounds(pos common.Position) bool {
 
 	return x >= 0 && x < m.Width && y >= 0 && y < m.Height
 }
+
+// Walkable returns true if the position is in bounds and troops can move onto it
+func (m *Map) Walkable(pos common.Position) bool {
+	return m.InBounds(pos) && m.Tiles[int(pos.Y)][int(pos.X)].Terrain.Walkable()
+}
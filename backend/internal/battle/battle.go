@@ -3,6 +3,7 @@ package battle
 import (
 	"cse-110-project-team-30/backend/internal/battle/arena"
 	"cse-110-project-team-30/backend/internal/battle/common"
+	"cse-110-project-team-30/backend/internal/battle/maps"
 	"cse-110-project-team-30/backend/internal/battle/troops"
 	"errors"
 	"fmt"
@@ -10,96 +11,119 @@ import (
 	"time"
 )
 
-const MaxTicks = 10000
+// MaxDuration is how much game time a battle lasts before it is called.
+const MaxDuration = 2000 * time.Second
 
-type Battle struct {
-	TickCount   int
-	IDMgr       int
-	Arena       *arena.Map
-	Troops      []troops.Entity
-	TowerStatus map[common.Team][]bool
-	Enabled     bool
-	OnDelete    func()
-}
+// MaxCatchUp is the most ticks Advance runs in one go. If the process was
+// starved for longer than that, the battle falls behind instead of
+// spiralling.
+const MaxCatchUp = 5
 
-func NewBattle() *Battle {
-	b := &Battle{
-		TickCount:   0,
-		IDMgr:       1,
-		Arena:       arena.NewMap(32, 32), // instantiate here
-		Troops:      []troops.Entity{},
-		TowerStatus: make(map[common.Team][]bool),
-	}
-	// Spawn castles for team 0 (e.g., player)
-
-	b.TowerStatus = map[common.Team][]bool{
-		0: {false, false, false},
-		1: {false, false, false},
-	}
-	b.Enabled = true
-	// Spawn castles for team 1 (e.g., enemy)
-	b.spawnTeamCastles(0)
-	b.spawnTeamCastles(1)
-	return b
-}
+// Reasons a spawn is rejected, so callers can tell players what went wrong.
+var (
+	ErrBattleOver         = errors.New("battle is over")
+	ErrTeamEliminated     = errors.New("your team has been eliminated")
+	ErrOutOfBounds        = errors.New("position out of arena bounds")
+	ErrBlockedTerrain     = errors.New("cannot spawn troop on blocked terrain")
+	ErrEnemyTerritory     = errors.New("cannot spawn troop in enemy territory")
+	ErrUnknownTroop       = errors.New("unknown troop type")
+	ErrNotInBattle        = errors.New("player is not in this battle")
+	ErrNotInHand          = errors.New("card is not in your hand")
+	ErrNotEnoughResources = errors.New("not enough resources")
+)
 
-func (b *Battle) spawnTeamCastles(team common.Team) {
-	numCastles := 3 // total castles per team
-	mapWidth := b.Arena.Width
-	mapHeight := b.Arena.Height
+// DefaultTickDuration is how much game time a tick covers.
+const DefaultTickDuration = 200 * time.Millisecond
 
-	var yFront int
-	var yKingOffset int
-	kingIndex := numCastles / 2 // middle castle
+// epsilon absorbs rounding when adding up tick durations in seconds.
+const epsilon = 1e-9
 
-	if team == 0 {
-		yFront = 6
-		yKingOffset = -2 // king tower one tile behind
-	} else {
-		yFront = mapWidth - 7
-		yKingOffset = 2
-	}
+type Battle struct {
+	TickCount    int
+	TickDuration time.Duration
+	IDMgr        int
+	Map          *maps.Definition
+	Arena        *arena.Map
+	Troops       []troops.Entity
+	Towers       []*Tower
+	DeployZones  map[common.Team][]common.Rect
+	Players      map[string]*Player
+	Teams        []common.Team
+	Eliminated   map[common.Team]bool
+	Winner       *common.Team // set once a single team is left standing
+	Enabled      bool
+	Paused       *Pause                        // set while the battle is paused
+	PauseLeft    map[common.Team]time.Duration // pause time each team has left
+	OnDelete     func()
 
-	for i := 0; i < numCastles; i++ {
-		x := (mapHeight / 4) * (i + 1)
-		y := yFront
-		isKing := i == kingIndex
+	listeners    []listener
+	nextListener int
+	accumulated  time.Duration // real time Advance hasn't ticked for yet
+}
 
-		if isKing {
-			y += yKingOffset
-		}
+// NewBattle creates a battle on the default map.
+func NewBattle() *Battle {
+	return NewBattleFromMap(maps.Default())
+}
 
-		b.TowerStatus[team][i] = true
-		pos := common.NewPosition(x, y)
-		var castle troops.Entity
-		if isKing {
-			castle = troops.NewKingCastle(-(i + 1 + int(team)*10), team, pos)
-		} else {
-			castle = troops.NewCastle(-(i + 1 + int(team)*10), team, pos)
-		}
-		b.Arena.AddTroop(int(pos.X), int(pos.Y), castle.GetTroop())
-		b.Troops = append(b.Troops, castle)
+// NewBattleFromMap creates a battle with the map's size, terrain, towers and
+// deploy zones.
+func NewBattleFromMap(def *maps.Definition) *Battle {
+	b := &Battle{
+		TickCount:    0,
+		TickDuration: DefaultTickDuration,
+		IDMgr:        1,
+		Map:          def,
+		Arena:        def.NewArena(),
+		Troops:       []troops.Entity{},
+		DeployZones:  newDeployZones(def),
+		Players:      make(map[string]*Player),
+		Teams:        def.Teams(),
+		Eliminated:   make(map[common.Team]bool),
+		Enabled:      true,
+		PauseLeft:    make(map[common.Team]time.Duration),
 	}
+	for _, team := range b.Teams {
+		b.PauseLeft[team] = PauseBudget
+	}
+	b.spawnTowers(def)
+	return b
 }
 
+// SpawnTroop spawns a troop for a team without any player paying for it.
 func (b *Battle) SpawnTroop(team common.Team, pos common.Position, troopType string) (*troops.Troop, error) {
+	return b.spawnTroop(team, "", pos, troopType)
+}
+
+func (b *Battle) spawnTroop(team common.Team, owner string, pos common.Position, troopType string) (*troops.Troop, error) {
 	if !b.Enabled {
-		return nil, errors.New("battle is over")
+		return nil, ErrBattleOver
+	}
+	if b.Paused != nil {
+		return nil, ErrPaused
+	}
+	if b.Eliminated[team] {
+		return nil, ErrTeamEliminated
 	}
 	if !b.Arena.InBounds(pos) {
-		return nil, errors.New("position out of arena bounds")
+		return nil, ErrOutOfBounds
 	}
-	if (team == common.Team(1) && pos.Y < float64(b.Arena.Height)/2) || (team == common.Team(0) && pos.Y >= float64(b.Arena.Height)/2) {
-		return nil, errors.New("cannot spawn troop in enemy territory")
+	if !b.Arena.Walkable(pos) {
+		return nil, ErrBlockedTerrain
+	}
+	if !b.CanDeploy(team, pos) {
+		return nil, ErrEnemyTerritory
 	}
 	newTroop := troops.NewTroopByType(troopType, team, pos)
 	if newTroop == nil || newTroop.GetTroop() == nil {
-		return nil, fmt.Errorf("failed to create troop of type %s", troopType)
+		return nil, fmt.Errorf("%w: %s", ErrUnknownTroop, troopType)
 	}
 	b.IDMgr++
 	newTroop.GetTroop().ID = b.IDMgr
+	newTroop.GetTroop().Owner = owner
 	b.Arena.AddTroop(int(pos.X), int(pos.Y), newTroop.GetTroop())
 	b.Troops = append(b.Troops, newTroop)
+	b.emit(Event{Type: EventSpawn, EntityID: b.IDMgr, Team: team, Owner: owner, TroopType: troopType, Position: &pos})
 	return newTroop.GetTroop(), nil
 }
 
@@ -111,31 +135,73 @@ func (b *Battle) PrintArenaWithMarkers(markers []common.Position) string {
 	return b.Arena.StringWithMarkers(markers)
 }
 
+// Advance adds real time to the battle's clock and runs a tick, followed by
+// afterTick if it isn't nil, for every TickDuration that has built up. Every
+// tick covers exactly TickDuration, so the battle plays out the same however
+// unevenly Advance is called. It returns how many ticks ran.
+func (b *Battle) Advance(elapsed time.Duration, afterTick func()) int {
+	if b.Paused != nil || b.TickDuration <= 0 {
+		b.accumulated = 0 // a pause is not time to catch up on
+		return 0
+	}
+	b.accumulated += elapsed
+	n := 0
+	for b.accumulated >= b.TickDuration {
+		if n == MaxCatchUp {
+			b.accumulated = 0
+			break
+		}
+		b.accumulated -= b.TickDuration
+		b.Tick()
+		if afterTick != nil {
+			afterTick()
+		}
+		n++
+	}
+	return n
+}
+
+// Tick advances the battle by one fixed step of TickDuration. A paused
+// battle stands still.
 func (b *Battle) Tick() {
+	if b.Paused != nil {
+		return
+	}
 	b.TickCount++
 	if !b.Enabled {
 		return
 	}
+	dt := b.TickDuration.Seconds()
+	b.regenResources(dt)
 	actions := b.calculateActions()
-	b.applyMovement(actions)
-	b.applyAttacks(actions)
+	b.applyMovement(actions, dt)
+	b.applyAttacks(actions, dt)
 	b.removeDeadTroops()
-	if b.TickCount >= MaxTicks {
+	if b.Elapsed() >= MaxDuration {
 		b.EndGame()
 	}
 }
 
+// Elapsed is how much game time the battle has run for.
+func (b *Battle) Elapsed() time.Duration {
+	return time.Duration(b.TickCount) * b.TickDuration
+}
+
+// EndGame stops the battle. If it has an OnDelete, that is called 5 seconds
+// later, once clients have seen the result; headless battles, such as bot
+// ladder matches, have none and end right away.
 func (b *Battle) EndGame() {
 	if !b.Enabled {
 		return
 	}
 	b.Enabled = false
-	go func() {
-		time.Sleep(5000 * time.Millisecond)
-		if b.OnDelete != nil {
-			b.OnDelete()
-		}
-	}()
+	b.emit(Event{Type: EventGameOver, Winner: b.Winner})
+	if onDelete := b.OnDelete; onDelete != nil {
+		go func() {
+			time.Sleep(5000 * time.Millisecond)
+			onDelete()
+		}()
+	}
 }
 
 // ------------------------
@@ -153,30 +219,56 @@ func (b *Battle) calculateActions() map[troops.Entity]troops.Action {
 // ------------------------
 // Step 2: Apply movement
 // ------------------------
-func (b *Battle) applyMovement(actions map[troops.Entity]troops.Action) {
+// Troops walk Speed tiles per second along their path, but at most one tile
+// per tick. Each troop also keeps its velocity so clients can interpolate
+// between updates and extrapolate past the last one.
+func (b *Battle) applyMovement(actions map[troops.Entity]troops.Action, dt float64) {
 	for troop, action := range actions {
-		oldX, oldY := int(math.Round(troop.GetTroop().Position.X)), int(math.Round(troop.GetTroop().Position.Y))
+		t := troop.GetTroop()
+		t.Velocity = common.Position{}
+		oldX, oldY := int(math.Round(t.Position.X)), int(math.Round(t.Position.Y))
 		newX, newY := int(math.Round(action.NextPosition.X)), int(math.Round(action.NextPosition.Y))
 
-		if !b.Arena.InBounds(common.NewPosition(newX, newY)) {
+		if (newX == oldX && newY == oldY) || !b.Arena.InBounds(common.NewPosition(newX, newY)) {
+			t.Stride = 0 // not walking: no saving up steps
+			continue
+		}
+		speed := min(t.Speed, 1/dt)
+		t.Velocity = common.Position{X: float64(newX-oldX) * speed, Y: float64(newY-oldY) * speed}
+		t.Stride += t.Speed * dt
+		if t.Stride < 1-epsilon {
 			continue
 		}
+		t.Stride = max(min(t.Stride-1, 1), 0) // keep the part of a tile walked past
 
-		b.removeTroopFromTile(troop.GetTroop(), oldX, oldY)
-		b.Arena.AddTroop(newX, newY, troop.GetTroop())
-		troop.GetTroop().Position = common.NewPosition(newX, newY)
+		b.removeTroopFromTile(t, oldX, oldY)
+		b.Arena.AddTroop(newX, newY, t)
+		t.Position = common.NewPosition(newX, newY)
 	}
 }
 
 // ------------------------
 // Step 3: Apply attacks
 // ------------------------
-func (b *Battle) applyAttacks(actions map[troops.Entity]troops.Action) {
-	for _, action := range actions {
-		if action.AttackTarget != nil {
-			target := action.AttackTarget.GetTroop()
-			target.Health -= action.Damage
+// A troop with a target in range strikes once its Cooldown since the last
+// strike has passed.
+func (b *Battle) applyAttacks(actions map[troops.Entity]troops.Action, dt float64) {
+	for attacker, action := range actions {
+		a := attacker.GetTroop()
+		a.TargetID = 0
+		a.Reload = max(a.Reload-dt, 0)
+		if action.AttackTarget == nil {
+			continue
+		}
+		target := action.AttackTarget.GetTroop()
+		a.TargetID = target.ID
+		if a.Reload > epsilon {
+			continue
 		}
+		a.Reload = a.Cooldown
+		target.Health -= action.Damage
+		b.emit(Event{Type: EventAttack, EntityID: a.ID, TargetID: target.ID, Team: a.Team, Owner: a.Owner, Amount: action.Damage})
+		b.emit(Event{Type: EventDamage, EntityID: target.ID, Team: target.Team, Owner: target.Owner, Amount: action.Damage, Health: max(target.Health, 0)})
 	}
 }
 
@@ -210,32 +302,30 @@ func (b *Battle) removeTroopFromBattle(t *troops.Troop) {
 // Step 4: remove dead troops
 
 func (b *Battle) removeDeadTroops() {
-	alive := make([]troops.Entity, 0, len(b.Troops))
-	for _, e := range b.Troops {
-		t := e.GetTroop()
-		if t.Health <= 0 {
-			x, y := int(math.Round(t.Position.X)), int(math.Round(t.Position.Y))
-			if b.Arena.InBounds(common.NewPosition(x, y)) {
-				b.removeTroopFromTile(t, x, y)
-			}
-			if t.Type == "Castle" || t.Type == "KingTower" {
-				idx := ((-t.ID) % 10) - 1
-				if idx >= 0 && idx < len(b.TowerStatus[t.Team]) {
-					b.TowerStatus[t.Team][idx] = false
+	for {
+		eliminated := len(b.Eliminated)
+		alive := make([]troops.Entity, 0, len(b.Troops))
+		for _, e := range b.Troops {
+			t := e.GetTroop()
+			if t.Health <= 0 {
+				x, y := int(math.Round(t.Position.X)), int(math.Round(t.Position.Y))
+				if b.Arena.InBounds(common.NewPosition(x, y)) {
+					b.removeTroopFromTile(t, x, y)
 				}
-			}
-			if t.Type == "KingTower" {
-				if ((-t.ID) % 10) == 2 {
-					//destroy all towers for that team
-					for i := range b.TowerStatus[t.Team] {
-						b.TowerStatus[t.Team][i] = false
-					}
-					b.EndGame()
+				pos := t.Position
+				b.emit(Event{Type: EventDeath, EntityID: t.ID, Team: t.Team, Owner: t.Owner, TroopType: t.Type, Position: &pos})
+				if tower := b.towerFor(t); tower != nil {
+					b.destroyTower(tower)
 				}
+				continue
 			}
-			continue
+			alive = append(alive, e)
+		}
+		b.Troops = alive
+
+		// Eliminating a team kills troops we may already have kept, so sweep again
+		if len(b.Eliminated) == eliminated {
+			return
 		}
-		alive = append(alive, e)
 	}
-	b.Troops = alive
 }
@@ -0,0 +1,502 @@
+package battle
+
+import (
+	"cse-110-project-team-30/backend/internal/battle/common"
+	"cse-110-project-team-30/backend/internal/battle/maps"
+	"errors"
+	"math"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func findTower(b *Battle, team common.Team, role maps.TowerRole) *Tower {
+	for _, tower := range b.Towers {
+		if tower.Owner == team && tower.Role == role {
+			return tower
+		}
+	}
+	return nil
+}
+
+func TestSideTowerDestroyed(t *testing.T) {
+	b := NewBattle()
+	side := findTower(b, common.TeamBlue, maps.TowerSide)
+	side.Entity.GetTroop().Health = 0
+	b.removeDeadTroops()
+
+	if side.Alive {
+		t.Fatal("expected side tower to be destroyed")
+	}
+	if !b.Enabled {
+		t.Fatal("losing a side tower should not end the game")
+	}
+	status := b.TowerStatus()
+	if status[common.TeamBlue][0] || !status[common.TeamBlue][1] || !status[common.TeamBlue][2] {
+		t.Errorf("unexpected blue tower status %v", status[common.TeamBlue])
+	}
+	for _, alive := range status[common.TeamRed] {
+		if !alive {
+			t.Errorf("red towers should be untouched, got %v", status[common.TeamRed])
+		}
+	}
+}
+
+func TestKingTowerEndsGame(t *testing.T) {
+	b := NewBattle()
+	king := findTower(b, common.TeamRed, maps.TowerKing)
+	king.Entity.GetTroop().Health = 0
+	goroutines := runtime.NumGoroutine()
+	b.removeDeadTroops()
+
+	if b.Enabled {
+		t.Fatal("expected losing the king tower to end the game")
+	}
+	if runtime.NumGoroutine() > goroutines {
+		t.Error("expected a battle without OnDelete to end without waiting to delete it")
+	}
+	if b.Winner == nil || *b.Winner != common.TeamBlue {
+		t.Errorf("expected blue to win, got %v", b.Winner)
+	}
+	for _, tower := range b.Towers {
+		if tower.Owner == common.TeamRed && tower.Alive {
+			t.Fatal("expected every red tower to fall with the king")
+		}
+	}
+}
+
+func TestFreeForAllLastTeamStanding(t *testing.T) {
+	def, err := maps.Get("ffa3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := NewBattleFromMap(def)
+	redTroop, err := b.SpawnTroop(common.TeamRed, common.NewPosition(1, 1), "SwordsmanOne")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	zones := map[common.Team][]common.Rect{}
+	for team, z := range b.DeployZones {
+		zones[team] = append([]common.Rect{}, z...)
+	}
+
+	findTower(b, common.TeamRed, maps.TowerKing).Entity.GetTroop().Health = 0
+	b.removeDeadTroops()
+	b.removeDeadTroops() // the side towers that fell with the king are cleared up
+	if !b.Enabled || b.Winner != nil {
+		t.Fatal("the game should go on while two teams remain")
+	}
+	delete(zones, common.TeamRed)
+	if !reflect.DeepEqual(b.DeployZones, zones) {
+		t.Errorf("expected side towers falling with their king to unlock no lanes, zones went from %v to %v", zones, b.DeployZones)
+	}
+	if !b.Eliminated[common.TeamRed] {
+		t.Error("expected red to be eliminated")
+	}
+	for _, e := range b.Troops {
+		if e.GetTroop() == redTroop {
+			t.Error("expected red's troops to be removed")
+		}
+	}
+	if _, err := b.SpawnTroop(common.TeamRed, common.NewPosition(1, 1), "SwordsmanOne"); err == nil {
+		t.Error("an eliminated team should not be able to spawn")
+	}
+
+	findTower(b, common.TeamBlue, maps.TowerKing).Entity.GetTroop().Health = 0
+	b.removeDeadTroops()
+	if b.Enabled {
+		t.Fatal("expected the game to end with one team left")
+	}
+	if b.Winner == nil || *b.Winner != common.TeamGreen {
+		t.Errorf("expected green to win, got %v", b.Winner)
+	}
+}
+
+func TestMapTowerStats(t *testing.T) {
+	def := maps.Default()
+	def.TowerStats.King.Health = 999
+	b := NewBattleFromMap(def)
+	king := findTower(b, common.TeamBlue, maps.TowerKing)
+	if got := king.Entity.GetTroop().Health; got != 999 {
+		t.Errorf("expected king health 999, got %d", got)
+	}
+}
+
+func TestSpawnRespectsMap(t *testing.T) {
+	def, err := maps.Get("river")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := NewBattleFromMap(def)
+
+	if _, err := b.SpawnTroop(common.TeamRed, common.NewPosition(2, 2), "SwordsmanOne"); err != nil {
+		t.Errorf("expected spawn in own zone to succeed, got %v", err)
+	}
+	if _, err := b.SpawnTroop(common.TeamRed, common.NewPosition(2, 20), "SwordsmanOne"); err == nil {
+		t.Error("expected spawn in enemy zone to fail")
+	}
+	if _, err := b.SpawnTroop(common.TeamRed, common.NewPosition(2, 15), "SwordsmanOne"); err == nil {
+		t.Error("expected spawn on water to fail")
+	}
+}
+
+func TestSideTowerUnlocksDeployZone(t *testing.T) {
+	b := NewBattle()
+	pos := common.NewPosition(2, 20)
+	if b.CanDeploy(common.TeamRed, pos) {
+		t.Fatal("red should not be able to deploy on blue's side yet")
+	}
+
+	var blueLeft *Tower
+	for _, tower := range b.Towers {
+		if tower.Owner == common.TeamBlue && tower.Role == maps.TowerSide && tower.Lane == 0 {
+			blueLeft = tower
+		}
+	}
+	blueLeft.Entity.GetTroop().Health = 0
+	b.removeDeadTroops()
+
+	if !b.CanDeploy(common.TeamRed, pos) {
+		t.Error("expected red to deploy in blue's left lane after the tower fell")
+	}
+	if b.CanDeploy(common.TeamRed, common.NewPosition(30, 20)) {
+		t.Error("blue's right lane should still be closed to red")
+	}
+	if !b.CanDeploy(common.TeamBlue, pos) {
+		t.Error("blue should keep its own zone")
+	}
+}
+
+func TestTeammatesHaveOwnHandsAndResources(t *testing.T) {
+	b := NewBattle()
+	b.AddPlayer("alice", common.TeamRed, []string{"SwordsmanOne"})
+	b.AddPlayer("bob", common.TeamRed, []string{"ArcherFour"})
+
+	troop, err := b.SpawnTroopAs("alice", common.NewPosition(2, 2), "SwordsmanOne")
+	if err != nil {
+		t.Fatalf("expected alice to spawn, got %v", err)
+	}
+	if troop.Owner != "alice" || troop.Team != common.TeamRed {
+		t.Errorf("expected red troop owned by alice, got owner %q team %d", troop.Owner, troop.Team)
+	}
+	if _, err := b.SpawnTroopAs("alice", common.NewPosition(2, 2), "ArcherFour"); err == nil {
+		t.Error("expected alice to be unable to play a card from bob's hand")
+	}
+
+	if got := b.Players["alice"].Resources; got != StartingResources-1 {
+		t.Errorf("expected alice to pay 1 resource, has %v", got)
+	}
+	if got := b.Players["bob"].Resources; got != StartingResources {
+		t.Errorf("bob's resources should be untouched, has %v", got)
+	}
+
+	b.Players["bob"].Resources = 3
+	if _, err := b.SpawnTroopAs("bob", common.NewPosition(3, 3), "ArcherFour"); err == nil {
+		t.Error("expected bob to be unable to afford a level 4 troop")
+	}
+}
+
+func TestHandsCycle(t *testing.T) {
+	b := NewBattle()
+	deck := []string{"SwordsmanOne", "ArcherOne", "SpearmanOne", "CavalryOne", "SwordsmanTwo"}
+	p := b.AddPlayer("alice", common.TeamRed, deck)
+	if strings.Join(p.Hand, ",") != "SwordsmanOne,ArcherOne,SpearmanOne,CavalryOne" {
+		t.Fatalf("expected the top of the deck in hand, got %v", p.Hand)
+	}
+	if p.CanPlay("SwordsmanTwo") {
+		t.Error("expected cards still in the deck to be unplayable")
+	}
+
+	if _, err := b.SpawnTroopAs("alice", common.NewPosition(2, 2), "ArcherOne"); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(p.Hand, ",") != "SwordsmanOne,SwordsmanTwo,SpearmanOne,CavalryOne" {
+		t.Errorf("expected the next card drawn in place of the one played, got %v", p.Hand)
+	}
+	if _, err := b.SpawnTroopAs("alice", common.NewPosition(3, 2), "ArcherOne"); !errors.Is(err, ErrNotInHand) {
+		t.Errorf("expected a played card to leave the hand, got %v", err)
+	}
+	if _, err := b.SpawnTroopAs("alice", common.NewPosition(3, 2), "SwordsmanTwo"); err != nil {
+		t.Fatal(err)
+	}
+	if !p.CanPlay("ArcherOne") {
+		t.Error("expected a played card to come back round")
+	}
+
+	if err := CheckDeck(deck); err != nil {
+		t.Errorf("expected the deck to be playable, got %v", err)
+	}
+	if err := CheckDeck([]string{"SwordsmanOne", "Dragon"}); !errors.Is(err, ErrUnknownTroop) {
+		t.Errorf("expected an unknown card to be refused, got %v", err)
+	}
+	if err := CheckDeck([]string{"SwordsmanOne", "SwordsmanOne"}); err == nil {
+		t.Error("expected a card twice in a deck to be refused")
+	}
+
+	empty := b.AddPlayer("bob", common.TeamBlue, nil)
+	if len(empty.Hand) != 0 || empty.CanPlay("SwordsmanOne") {
+		t.Error("expected a player without a deck to have no cards")
+	}
+}
+
+func TestEventStream(t *testing.T) {
+	b := NewBattle()
+	var events []Event
+	b.Subscribe(func(e Event) { events = append(events, e) })
+	stats := NewStats()
+	b.Subscribe(stats.Record)
+
+	b.AddPlayer("alice", common.TeamRed, []string{"CavalryOne"})
+	troop, err := b.SpawnTroopAs("alice", common.NewPosition(16, 15), "CavalryOne")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0].Type != EventSpawn || events[0].EntityID != troop.ID || events[0].Owner != "alice" {
+		t.Fatalf("expected a spawn event for alice's troop, got %+v", events)
+	}
+
+	king := findTower(b, common.TeamBlue, maps.TowerKing)
+	king.Entity.GetTroop().Health = 0
+	b.removeDeadTroops()
+
+	seen := map[EventType]int{}
+	for _, e := range events {
+		seen[e.Type]++
+	}
+	if seen[EventDeath] == 0 || seen[EventTeamEliminated] != 1 || seen[EventGameOver] != 1 {
+		t.Errorf("missing events for the king falling: %v", seen)
+	}
+	if seen[EventTowerDestroyed] != 3 {
+		t.Errorf("expected all 3 blue towers to be reported destroyed, got %d", seen[EventTowerDestroyed])
+	}
+	for _, e := range events {
+		if e.Type == EventGameOver && (e.Winner == nil || *e.Winner != common.TeamRed) {
+			t.Errorf("expected red to win, got %+v", e)
+		}
+	}
+	if stats.Spawned["alice"] != 1 {
+		t.Errorf("expected stats to count alice's spawn, got %v", stats.Spawned)
+	}
+}
+
+func TestUnsubscribe(t *testing.T) {
+	b := NewBattle()
+	calls := 0
+	unsubscribe := b.Subscribe(func(Event) { calls++ })
+	b.SpawnTroop(common.TeamRed, common.NewPosition(1, 1), "SwordsmanOne")
+	unsubscribe()
+	b.SpawnTroop(common.TeamRed, common.NewPosition(2, 1), "SwordsmanOne")
+	if calls != 1 {
+		t.Errorf("expected 1 event before unsubscribing, got %d", calls)
+	}
+}
+
+func TestFogOfWar(t *testing.T) {
+	def, err := maps.Get("fog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := NewBattleFromMap(def)
+	near, err := b.SpawnTroop(common.TeamBlue, common.NewPosition(16, 17), "SwordsmanOne")
+	if err != nil {
+		t.Fatal(err)
+	}
+	far, err := b.SpawnTroop(common.TeamBlue, common.NewPosition(2, 30), "SwordsmanOne")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	red := b.VisionFor(common.TeamRed)
+	if red.CanSee(far.Position) {
+		t.Error("red should not see deep into blue's side")
+	}
+	seen := map[int]bool{}
+	for _, e := range red.Visible(b.Troops) {
+		seen[e.GetTroop().ID] = true
+	}
+	if seen[near.ID] || seen[far.ID] {
+		t.Errorf("red should not see either blue troop yet, sees %v", seen)
+	}
+	if len(seen) != len(b.Towers) {
+		t.Errorf("every tower should stay visible, got %d of %d", len(seen), len(b.Towers))
+	}
+
+	scout, err := b.SpawnTroop(common.TeamRed, common.NewPosition(16, 13), "SwordsmanOne")
+	if err != nil {
+		t.Fatal(err)
+	}
+	red = b.VisionFor(common.TeamRed)
+	if !red.CanSee(near.Position) || red.CanSee(far.Position) {
+		t.Error("red's scout should reveal the nearby troop only")
+	}
+	if !red.CanSee(scout.Position) {
+		t.Error("a team should see its own troops")
+	}
+
+	if NewBattle().VisionFor(common.TeamRed) != nil {
+		t.Error("maps without fog should show everything")
+	}
+}
+
+func TestTroopsReportMotion(t *testing.T) {
+	b := NewBattle()
+	troop, err := b.SpawnTroop(common.TeamRed, common.NewPosition(16, 12), "SwordsmanOne")
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := troop.Position
+	b.Tick()
+
+	step := common.Position{X: troop.Position.X - start.X, Y: troop.Position.Y - start.Y}
+	if step == (common.Position{}) {
+		t.Fatal("expected the troop to walk towards the enemy")
+	}
+	perSecond := 1 / b.TickDuration.Seconds()
+	if troop.Velocity.X != step.X*perSecond || troop.Velocity.Y != step.Y*perSecond {
+		t.Errorf("expected velocity %v tiles/s, got %v", step, troop.Velocity)
+	}
+	if troop.TargetID != 0 {
+		t.Errorf("a walking troop should have no target, got %d", troop.TargetID)
+	}
+
+	king := findTower(b, common.TeamBlue, maps.TowerKing)
+	b.removeTroopFromTile(troop, int(troop.Position.X), int(troop.Position.Y))
+	troop.Position = common.NewPosition(king.X, king.Y-1)
+	b.Arena.AddTroop(king.X, king.Y-1, troop)
+	b.Tick()
+	if troop.TargetID != king.Entity.GetTroop().ID {
+		t.Errorf("expected the troop to target the king tower, got %d", troop.TargetID)
+	}
+	if troop.Velocity != (common.Position{}) {
+		t.Errorf("an attacking troop should stand still, got velocity %v", troop.Velocity)
+	}
+}
+
+func TestPauseAndResume(t *testing.T) {
+	b := NewBattle()
+	b.AddPlayer("alice", common.TeamRed, nil)
+	b.AddPlayer("bob", common.TeamBlue, []string{"SwordsmanOne"})
+	start := time.Now()
+
+	if err := b.Pause("alice", start); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Pause("bob", start); !errors.Is(err, ErrPaused) {
+		t.Errorf("expected a second pause to fail with ErrPaused, got %v", err)
+	}
+	b.Tick()
+	if b.TickCount != 0 {
+		t.Error("expected a paused battle not to tick")
+	}
+	if _, err := b.SpawnTroopAs("bob", common.NewPosition(2, 2), "SwordsmanOne"); !errors.Is(err, ErrPaused) {
+		t.Errorf("expected spawning while paused to fail, got %v", err)
+	}
+
+	// Both sides have to agree to carry on
+	if err := b.Resume("alice", start.Add(10*time.Second)); err != nil || b.Paused == nil {
+		t.Fatalf("expected the battle to stay paused until bob resumes too, err=%v", err)
+	}
+	if err := b.Resume("bob", start.Add(10*time.Second)); err != nil || b.Paused != nil {
+		t.Fatalf("expected the battle to resume, err=%v", err)
+	}
+	if left := b.PauseLeft[common.TeamRed]; left != PauseBudget-10*time.Second {
+		t.Errorf("expected red to be charged 10s, has %v left", left)
+	}
+	if b.PauseLeft[common.TeamBlue] != PauseBudget {
+		t.Error("expected blue's budget to be untouched")
+	}
+
+	// A pause runs out on its own, and the budget with it
+	later := start.Add(time.Minute)
+	b.Pause("alice", later)
+	b.CheckPause(later.Add(PauseTimeout - time.Second))
+	if b.Paused == nil {
+		t.Fatal("expected the pause to last until its timeout")
+	}
+	b.CheckPause(later.Add(PauseTimeout))
+	if b.Paused != nil {
+		t.Fatal("expected the pause to end after its timeout")
+	}
+	b.Pause("alice", later)
+	b.CheckPause(later.Add(PauseTimeout))
+	if err := b.Pause("alice", later); !errors.Is(err, ErrNoPauseBudget) {
+		t.Errorf("expected red to be out of pause time, got %v", err)
+	}
+}
+
+func TestForfeit(t *testing.T) {
+	b := NewBattle()
+	b.AddPlayer("alice", common.TeamRed, nil)
+	b.AddPlayer("bob", common.TeamBlue, nil)
+	b.Pause("bob", time.Now())
+
+	if err := b.Forfeit("alice"); err != nil {
+		t.Fatal(err)
+	}
+	if b.Enabled || b.Paused != nil {
+		t.Fatal("expected a forfeit to end the game, pause or not")
+	}
+	if b.Winner == nil || *b.Winner != common.TeamBlue {
+		t.Errorf("expected blue to win, got %v", b.Winner)
+	}
+	if err := b.Forfeit("bob"); !errors.Is(err, ErrBattleOver) {
+		t.Errorf("expected no forfeits after the game, got %v", err)
+	}
+}
+
+func TestFixedTimestep(t *testing.T) {
+	b := NewBattle()
+	b.TickDuration = 50 * time.Millisecond
+
+	// Time builds up until a whole tick is due, and a long stall only
+	// catches up so far
+	if n := b.Advance(30*time.Millisecond, nil); n != 0 {
+		t.Errorf("expected no tick for 30ms, got %d", n)
+	}
+	acted := 0
+	if n := b.Advance(30*time.Millisecond, func() { acted++ }); n != 1 || acted != 1 {
+		t.Errorf("expected one tick and one call after 60ms, got %d and %d", n, acted)
+	}
+	if n := b.Advance(10*time.Second, nil); n != MaxCatchUp {
+		t.Errorf("expected a stall to catch up %d ticks, got %d", MaxCatchUp, n)
+	}
+	if n := b.Advance(0, nil); n != 0 {
+		t.Errorf("expected the rest of a stall to be dropped, got %d ticks", n)
+	}
+
+	// At 20 ticks a second a troop walking 5 tiles a second steps every
+	// fourth tick
+	troop, err := b.SpawnTroop(common.TeamRed, common.NewPosition(16, 12), "SwordsmanOne")
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := troop.Position
+	for i := 1; i <= 8; i++ {
+		b.Tick()
+		moved := math.Abs(troop.Position.X-start.X) + math.Abs(troop.Position.Y-start.Y)
+		if want := float64(i / 4); moved != want {
+			t.Errorf("tick %d: expected %v tiles walked, got %v", i, want, moved)
+		}
+	}
+
+	// ...and strikes once per Cooldown however short the ticks are
+	king := findTower(b, common.TeamBlue, maps.TowerKing)
+	b.removeTroopFromTile(troop, int(troop.Position.X), int(troop.Position.Y))
+	troop.Position = common.NewPosition(king.X, king.Y-1)
+	b.Arena.AddTroop(king.X, king.Y-1, troop)
+	strikes := 0
+	b.Subscribe(func(e Event) {
+		if e.Type == EventAttack && e.EntityID == troop.ID {
+			strikes++
+		}
+	})
+	for i := 0; i < 20; i++ {
+		b.Tick()
+	}
+	if want := int(time.Second.Seconds() / troop.Cooldown); strikes != want {
+		t.Errorf("expected %d strikes in a second, got %d", want, strikes)
+	}
+}
@@ -1,5 +1,7 @@
 package common
 
+import "fmt"
+
 type Position struct {
 	X, Y float64
 }
@@ -16,4 +18,40 @@ type Team int
 const (
 	TeamRed Team = iota
 	TeamBlue
+	TeamGreen
+	TeamYellow
 )
+
+var teamNames = []string{"red", "blue", "green", "yellow"}
+
+// String returns the team's color name, e.g. "red".
+func (t Team) String() string {
+	if t >= 0 && int(t) < len(teamNames) {
+		return teamNames[t]
+	}
+	return fmt.Sprintf("team%d", int(t))
+}
+
+// ParseTeam turns a color name back into a Team.
+func ParseTeam(s string) (Team, bool) {
+	for i, name := range teamNames {
+		if name == s {
+			return Team(i), true
+		}
+	}
+	return 0, false
+}
+
+// Rect is an axis-aligned block of tiles starting at (X, Y).
+type Rect struct {
+	X int `json:"x"`
+	Y int `json:"y"`
+	W int `json:"w"`
+	H int `json:"h"`
+}
+
+// Contains reports whether the tile at pos lies inside the rectangle.
+func (r Rect) Contains(pos Position) bool {
+	x, y := int(pos.X), int(pos.Y)
+	return x >= r.X && x < r.X+r.W && y >= r.Y && y < r.Y+r.H
+}
@@ -0,0 +1,40 @@
+package battle
+
+import (
+	"cse-110-project-team-30/backend/internal/battle/common"
+	"cse-110-project-team-30/backend/internal/battle/maps"
+)
+
+// newDeployZones copies the map's starting zones so the battle can grow them
+// without touching the definition.
+func newDeployZones(def *maps.Definition) map[common.Team][]common.Rect {
+	zones := make(map[common.Team][]common.Rect, len(def.DeployZones))
+	for team, rects := range def.DeployZones {
+		zones[team] = append([]common.Rect{}, rects...)
+	}
+	return zones
+}
+
+// CanDeploy reports whether the team may spawn troops at pos.
+func (b *Battle) CanDeploy(team common.Team, pos common.Position) bool {
+	for _, zone := range b.DeployZones[team] {
+		if zone.Contains(pos) {
+			return true
+		}
+	}
+	return false
+}
+
+// unlockDeployZones opens up the area behind a fallen side tower to every
+// team other than its owner.
+func (b *Battle) unlockDeployZones(tower *Tower) {
+	if len(tower.Unlocks) == 0 {
+		return
+	}
+	for team := range b.DeployZones {
+		if team == tower.Owner {
+			continue
+		}
+		b.DeployZones[team] = append(b.DeployZones[team], tower.Unlocks...)
+	}
+}
@@ -0,0 +1,65 @@
+package battle
+
+import "cse-110-project-team-30/backend/internal/battle/common"
+
+// EventType names something that happened during a tick.
+type EventType string
+
+const (
+	EventSpawn          EventType = "spawn"          // a troop entered the arena
+	EventAttack         EventType = "attack"         // EntityID hit TargetID for Amount
+	EventDamage         EventType = "damage"         // EntityID lost Amount, Health is left
+	EventDeath          EventType = "death"          // EntityID was removed from the arena
+	EventTowerDestroyed EventType = "towerDestroyed" // tower TowerID of Team fell
+	EventTeamEliminated EventType = "teamEliminated" // Team lost its king tower
+	EventGameOver       EventType = "gameOver"       // the battle ended; Winner is nil on a draw
+	EventPaused         EventType = "paused"         // Owner paused the battle for Team
+	EventResumed        EventType = "resumed"        // the pause Team asked for is over
+	EventForfeit        EventType = "forfeit"        // Owner conceded for Team
+)
+
+// Event is one thing that happened in the battle. Only the fields that make
+// sense for its Type are set.
+type Event struct {
+	Tick      int              `json:"tick"`
+	Type      EventType        `json:"type"`
+	EntityID  int              `json:"entityID,omitempty"`
+	TargetID  int              `json:"targetID,omitempty"`
+	TowerID   int              `json:"towerID,omitempty"`
+	Team      common.Team      `json:"team"`
+	Owner     string           `json:"owner,omitempty"`
+	TroopType string           `json:"troopType,omitempty"`
+	Amount    int              `json:"amount,omitempty"`
+	Health    int              `json:"health,omitempty"`
+	Position  *common.Position `json:"position,omitempty"`
+	Winner    *common.Team     `json:"winner,omitempty"`
+}
+
+type listener struct {
+	id int
+	fn func(Event)
+}
+
+// Subscribe registers fn to be called for every event, in order, on the
+// goroutine that mutates the battle. It returns a function that removes the
+// subscription.
+func (b *Battle) Subscribe(fn func(Event)) (unsubscribe func()) {
+	b.nextListener++
+	id := b.nextListener
+	b.listeners = append(b.listeners, listener{id: id, fn: fn})
+	return func() {
+		for i, l := range b.listeners {
+			if l.id == id {
+				b.listeners = append(b.listeners[:i:i], b.listeners[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+func (b *Battle) emit(e Event) {
+	e.Tick = b.TickCount
+	for _, l := range b.listeners {
+		l.fn(e)
+	}
+}
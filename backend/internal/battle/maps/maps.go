@@ -0,0 +1,329 @@
+// Package maps loads arena definitions from JSON so new arenas can ship
+// without touching Go code.
+package maps
+
+import (
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"cse-110-project-team-30/backend/internal/battle/arena"
+	"cse-110-project-team-30/backend/internal/battle/common"
+	"cse-110-project-team-30/backend/internal/battle/troops"
+)
+
+// DefaultName is the map used when a room does not pick one.
+const DefaultName = "classic"
+
+//go:embed *.json
+var builtin embed.FS
+
+// TowerRole tells apart the king tower, whose loss ends the game for its
+// owner, from the side towers guarding each lane.
+type TowerRole string
+
+const (
+	TowerKing TowerRole = "king"
+	TowerSide TowerRole = "side"
+)
+
+// Tower describes a tower placed when the battle starts.
+// Lane and Unlocks are only meaningful for side towers: Unlocks is the area
+// of the owner's side that opposing teams may deploy into once the tower
+// falls.
+type Tower struct {
+	ID      int           `json:"id"`
+	Role    TowerRole     `json:"role"`
+	Lane    int           `json:"lane"`
+	Owner   common.Team   `json:"owner"`
+	X       int           `json:"x"`
+	Y       int           `json:"y"`
+	Unlocks []common.Rect `json:"unlocks,omitempty"`
+}
+
+func (t Tower) Position() common.Position {
+	return common.NewPosition(t.X, t.Y)
+}
+
+// TowerStats holds the castle stats for each tower role.
+type TowerStats struct {
+	Side troops.CastleStats `json:"side"`
+	King troops.CastleStats `json:"king"`
+}
+
+// Fog turns on fog of war: each team only sees the tiles within sight of
+// its own troops and towers. Sight ranges are in tiles.
+type Fog struct {
+	TroopSight float64 `json:"troopSight"`
+	TowerSight float64 `json:"towerSight"`
+}
+
+// DefaultFog is used for sight ranges a map with fog leaves out.
+var DefaultFog = Fog{TroopSight: 6, TowerSight: 8}
+
+// Definition is a parsed map file.
+//
+// Terrain is one string per row; '.' is grass, '~' is water and '#' is a
+// wall. An empty Terrain means the whole map is grass.
+type Definition struct {
+	Name        string                        `json:"name"`
+	Width       int                           `json:"width"`
+	Height      int                           `json:"height"`
+	Terrain     []string                      `json:"terrain"`
+	TowerStats  TowerStats                    `json:"towerStats"`
+	Towers      []Tower                       `json:"towers"`
+	DeployZones map[common.Team][]common.Rect `json:"deployZones"`
+	Fog         *Fog                          `json:"fog,omitempty"` // nil means every team sees the whole map
+}
+
+var terrainLegend = map[rune]arena.Terrain{
+	'.': arena.TerrainGrass,
+	'~': arena.TerrainWater,
+	'#': arena.TerrainWall,
+}
+
+var (
+	mu     sync.RWMutex
+	custom = map[string][]byte{}
+)
+
+// Parse decodes and validates a map definition. Tower stats left out of the
+// file fall back to the default castle stats, and so do missing sight ranges
+// on a map with fog.
+func Parse(data []byte) (*Definition, error) {
+	def := &Definition{
+		TowerStats: TowerStats{
+			Side: troops.DefaultCastleStats,
+			King: troops.DefaultKingCastleStats,
+		},
+	}
+	if err := json.Unmarshal(data, def); err != nil {
+		return nil, fmt.Errorf("invalid map json: %w", err)
+	}
+	if def.Fog != nil {
+		if def.Fog.TroopSight == 0 {
+			def.Fog.TroopSight = DefaultFog.TroopSight
+		}
+		if def.Fog.TowerSight == 0 {
+			def.Fog.TowerSight = DefaultFog.TowerSight
+		}
+	}
+	if err := def.Validate(); err != nil {
+		return nil, fmt.Errorf("map %q: %w", def.Name, err)
+	}
+	return def, nil
+}
+
+// Load reads a map definition from a file.
+func Load(path string) (*Definition, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data)
+}
+
+// LoadDir registers every *.json map in dir, so it can be picked by name.
+// A custom map with the same name as a built-in one replaces it.
+func LoadDir(dir string) ([]string, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	names := []string{}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return names, err
+		}
+		def, err := Parse(data)
+		if err != nil {
+			return names, fmt.Errorf("%s: %w", path, err)
+		}
+		mu.Lock()
+		custom[def.Name] = data
+		mu.Unlock()
+		names = append(names, def.Name)
+	}
+	return names, nil
+}
+
+// Get returns a fresh copy of the named map, so callers are free to modify it.
+func Get(name string) (*Definition, error) {
+	mu.RLock()
+	data, ok := custom[name]
+	mu.RUnlock()
+	if !ok {
+		var err error
+		data, err = builtin.ReadFile(name + ".json")
+		if err != nil {
+			return nil, fmt.Errorf("unknown map %q", name)
+		}
+	}
+	return Parse(data)
+}
+
+// Default returns the default map. The built-in maps are validated by the
+// tests, so failing to load one is a programming error.
+func Default() *Definition {
+	def, err := Get(DefaultName)
+	if err != nil {
+		panic(err)
+	}
+	return def
+}
+
+// Names lists every map that can be passed to Get.
+func Names() []string {
+	seen := map[string]bool{}
+	entries, _ := builtin.ReadDir(".")
+	for _, e := range entries {
+		seen[strings.TrimSuffix(e.Name(), ".json")] = true
+	}
+	mu.RLock()
+	for name := range custom {
+		seen[name] = true
+	}
+	mu.RUnlock()
+
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Validate checks that the map is playable: terrain matches the size, towers
+// stand on walkable tiles with unique IDs, and every team has a king tower
+// and somewhere to deploy.
+func (d *Definition) Validate() error {
+	if d.Name == "" {
+		return errors.New("missing name")
+	}
+	if d.Width <= 0 || d.Height <= 0 {
+		return fmt.Errorf("invalid size %dx%d", d.Width, d.Height)
+	}
+	if len(d.Terrain) != 0 {
+		if len(d.Terrain) != d.Height {
+			return fmt.Errorf("terrain has %d rows, want %d", len(d.Terrain), d.Height)
+		}
+		for y, row := range d.Terrain {
+			if len([]rune(row)) != d.Width {
+				return fmt.Errorf("terrain row %d has %d tiles, want %d", y, len([]rune(row)), d.Width)
+			}
+			for _, r := range row {
+				if _, ok := terrainLegend[r]; !ok {
+					return fmt.Errorf("terrain row %d: unknown tile %q", y, r)
+				}
+			}
+		}
+	}
+
+	ids := map[int]bool{}
+	kings := map[common.Team]int{}
+	for _, t := range d.Towers {
+		if t.ID <= 0 {
+			return fmt.Errorf("tower id %d must be positive", t.ID)
+		}
+		if ids[t.ID] {
+			return fmt.Errorf("duplicate tower id %d", t.ID)
+		}
+		ids[t.ID] = true
+		switch t.Role {
+		case TowerKing:
+			kings[t.Owner]++
+		case TowerSide:
+		default:
+			return fmt.Errorf("tower %d: unknown role %q", t.ID, t.Role)
+		}
+		if t.X < 0 || t.X >= d.Width || t.Y < 0 || t.Y >= d.Height {
+			return fmt.Errorf("tower %d is out of bounds", t.ID)
+		}
+		if !d.TerrainAt(t.X, t.Y).Walkable() {
+			return fmt.Errorf("tower %d is not on walkable terrain", t.ID)
+		}
+		if t.Role == TowerKing && len(t.Unlocks) != 0 {
+			return fmt.Errorf("tower %d: only side towers can unlock deploy zones", t.ID)
+		}
+		for _, r := range t.Unlocks {
+			if !d.containsRect(r) {
+				return fmt.Errorf("tower %d unlocks a zone outside the map", t.ID)
+			}
+		}
+	}
+	if len(kings) == 0 {
+		return errors.New("map has no king towers")
+	}
+	for team, n := range kings {
+		if n != 1 {
+			return fmt.Errorf("team %d has %d king towers, want 1", team, n)
+		}
+		if len(d.DeployZones[team]) == 0 {
+			return fmt.Errorf("team %d has no deploy zone", team)
+		}
+	}
+	// A team without a king can never be eliminated, so the game couldn't end
+	for _, t := range d.Towers {
+		if kings[t.Owner] == 0 {
+			return fmt.Errorf("tower %d belongs to team %d, which has no king tower", t.ID, t.Owner)
+		}
+	}
+	for team := range d.DeployZones {
+		if kings[team] == 0 {
+			return fmt.Errorf("team %d has a deploy zone but no king tower", team)
+		}
+	}
+	if d.Fog != nil && (d.Fog.TroopSight < 0 || d.Fog.TowerSight < 0) {
+		return errors.New("fog sight ranges must not be negative")
+	}
+	for team, zones := range d.DeployZones {
+		for _, r := range zones {
+			if !d.containsRect(r) {
+				return fmt.Errorf("team %d has a deploy zone outside the map", team)
+			}
+		}
+	}
+	return nil
+}
+
+func (d *Definition) containsRect(r common.Rect) bool {
+	return r.W > 0 && r.H > 0 && r.X >= 0 && r.Y >= 0 && r.X+r.W <= d.Width && r.Y+r.H <= d.Height
+}
+
+// Teams returns every team that owns a king tower, in ascending order.
+func (d *Definition) Teams() []common.Team {
+	teams := []common.Team{}
+	for _, t := range d.Towers {
+		if t.Role == TowerKing {
+			teams = append(teams, t.Owner)
+		}
+	}
+	sort.Slice(teams, func(i, j int) bool { return teams[i] < teams[j] })
+	return teams
+}
+
+// TerrainAt returns the terrain of the tile at (x, y).
+func (d *Definition) TerrainAt(x, y int) arena.Terrain {
+	if len(d.Terrain) == 0 {
+		return arena.TerrainGrass
+	}
+	return terrainLegend[[]rune(d.Terrain[y])[x]]
+}
+
+// NewArena builds an empty arena with the map's size and terrain.
+func (d *Definition) NewArena() *arena.Map {
+	m := arena.NewMap(d.Width, d.Height)
+	for y := 0; y < d.Height; y++ {
+		for x := 0; x < d.Width; x++ {
+			m.Tiles[y][x].Terrain = d.TerrainAt(x, y)
+		}
+	}
+	return m
+}
@@ -0,0 +1,47 @@
+package maps
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBuiltinMapsAreValid(t *testing.T) {
+	for _, name := range Names() {
+		if _, err := Get(name); err != nil {
+			t.Errorf("built-in map %s: %v", name, err)
+		}
+	}
+}
+
+func TestParseRejectsInvalidMaps(t *testing.T) {
+	cases := map[string]string{
+		"no king":                  `{"name":"x","width":4,"height":4,"towers":[{"id":1,"role":"side","owner":0,"x":1,"y":1}]}`,
+		"bad terrain":              `{"name":"x","width":2,"height":1,"terrain":[".?"],"towers":[{"id":1,"role":"king","owner":0,"x":0,"y":0}],"deployZones":{"0":[{"x":0,"y":0,"w":2,"h":1}]}}`,
+		"tower on wall":            `{"name":"x","width":2,"height":1,"terrain":["#."],"towers":[{"id":1,"role":"king","owner":0,"x":0,"y":0}],"deployZones":{"0":[{"x":0,"y":0,"w":2,"h":1}]}}`,
+		"duplicate id":             `{"name":"x","width":4,"height":4,"towers":[{"id":1,"role":"king","owner":0,"x":1,"y":1},{"id":1,"role":"king","owner":1,"x":2,"y":2}],"deployZones":{"0":[{"x":0,"y":0,"w":4,"h":2}],"1":[{"x":0,"y":2,"w":4,"h":2}]}}`,
+		"no deploy zone":           `{"name":"x","width":4,"height":4,"towers":[{"id":1,"role":"king","owner":0,"x":1,"y":1}]}`,
+		"side tower without king":  `{"name":"x","width":4,"height":4,"towers":[{"id":1,"role":"king","owner":0,"x":1,"y":1},{"id":2,"role":"side","owner":1,"x":2,"y":2}],"deployZones":{"0":[{"x":0,"y":0,"w":4,"h":4}]}}`,
+		"deploy zone without king": `{"name":"x","width":4,"height":4,"towers":[{"id":1,"role":"king","owner":0,"x":1,"y":1}],"deployZones":{"0":[{"x":0,"y":0,"w":4,"h":2}],"1":[{"x":0,"y":2,"w":4,"h":2}]}}`,
+	}
+	for name, data := range cases {
+		if _, err := Parse([]byte(data)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestParseDefaultsTowerStats(t *testing.T) {
+	def, err := Parse([]byte(`{"name":"x","width":4,"height":4,"towers":[{"id":1,"role":"king","owner":0,"x":1,"y":1}],"deployZones":{"0":[{"x":0,"y":0,"w":4,"h":4}]}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if def.TowerStats.King.Health == 0 || def.TowerStats.Side.Health == 0 {
+		t.Errorf("expected default tower stats, got %+v", def.TowerStats)
+	}
+}
+
+func TestGetUnknownMap(t *testing.T) {
+	if _, err := Get("does-not-exist"); err == nil || !strings.Contains(err.Error(), "unknown map") {
+		t.Errorf("expected unknown map error, got %v", err)
+	}
+}
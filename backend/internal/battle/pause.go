@@ -0,0 +1,115 @@
+package battle
+
+import (
+	"errors"
+	"time"
+
+	"cse-110-project-team-30/backend/internal/battle/common"
+)
+
+// Pause settings shared by every team.
+const (
+	PauseBudget  = time.Minute      // total time each team may keep the battle paused
+	PauseTimeout = 30 * time.Second // a single pause ends on its own after this long
+)
+
+// Reasons a pause or resume is rejected.
+var (
+	ErrPaused        = errors.New("battle is paused")
+	ErrNotPaused     = errors.New("battle is not paused")
+	ErrNoPauseBudget = errors.New("your team has no pause time left")
+)
+
+// Pause is a break in the battle. Nothing moves and nobody can spawn until
+// every team still in the game asks to resume, or Until passes.
+type Pause struct {
+	Team   common.Team          // who asked for it
+	Since  time.Time            // when it started
+	Until  time.Time            // when it ends on its own
+	Resume map[common.Team]bool // teams that want to carry on
+}
+
+// Pause stops the battle on a player's behalf. It lasts at most
+// PauseTimeout or whatever is left of the team's pause budget.
+func (b *Battle) Pause(playerID string, now time.Time) error {
+	p, ok := b.Players[playerID]
+	switch {
+	case !ok:
+		return ErrNotInBattle
+	case !b.Enabled:
+		return ErrBattleOver
+	case b.Eliminated[p.Team]:
+		return ErrTeamEliminated
+	case b.Paused != nil:
+		return ErrPaused
+	case b.PauseLeft[p.Team] <= 0:
+		return ErrNoPauseBudget
+	}
+	b.Paused = &Pause{
+		Team:   p.Team,
+		Since:  now,
+		Until:  now.Add(min(PauseTimeout, b.PauseLeft[p.Team])),
+		Resume: make(map[common.Team]bool),
+	}
+	b.emit(Event{Type: EventPaused, Team: p.Team, Owner: playerID})
+	return nil
+}
+
+// Resume records that the player's team wants to carry on, and resumes the
+// battle once every team still in the game does.
+func (b *Battle) Resume(playerID string, now time.Time) error {
+	p, ok := b.Players[playerID]
+	switch {
+	case !ok:
+		return ErrNotInBattle
+	case b.Paused == nil:
+		return ErrNotPaused
+	case b.Eliminated[p.Team]:
+		return ErrTeamEliminated
+	}
+	b.Paused.Resume[p.Team] = true
+	for _, team := range b.RemainingTeams() {
+		if !b.Paused.Resume[team] {
+			return nil
+		}
+	}
+	b.unpause(now)
+	return nil
+}
+
+// CheckPause resumes the battle if its pause has run out. Whoever drives the
+// ticks calls it while the battle is paused.
+func (b *Battle) CheckPause(now time.Time) {
+	if b.Paused != nil && !now.Before(b.Paused.Until) {
+		b.unpause(now)
+	}
+}
+
+// unpause ends the pause and charges it to the team that asked for it.
+func (b *Battle) unpause(now time.Time) {
+	pause := b.Paused
+	b.Paused = nil
+	used := min(now.Sub(pause.Since), b.PauseLeft[pause.Team])
+	b.PauseLeft[pause.Team] -= max(used, 0)
+	b.emit(Event{Type: EventResumed, Team: pause.Team})
+}
+
+// Forfeit concedes the battle for a player's team. The team is eliminated
+// as if its king tower fell, so the game ends once one team is left.
+func (b *Battle) Forfeit(playerID string) error {
+	p, ok := b.Players[playerID]
+	switch {
+	case !ok:
+		return ErrNotInBattle
+	case !b.Enabled:
+		return ErrBattleOver
+	case b.Eliminated[p.Team]:
+		return ErrTeamEliminated
+	}
+	b.emit(Event{Type: EventForfeit, Team: p.Team, Owner: playerID})
+	b.eliminateTeam(p.Team)
+	if !b.Enabled {
+		b.Paused = nil
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+package battle
+
+import (
+	"cse-110-project-team-30/backend/internal/battle/common"
+	"cse-110-project-team-30/backend/internal/battle/troops"
+	"fmt"
+	"sort"
+)
+
+// Resource pool settings shared by every player.
+const (
+	StartingResources  = 5.0
+	MaxResources       = 10.0
+	ResourcesPerSecond = 0.5
+)
+
+// HandSize is how many cards a player holds at once.
+const HandSize = 4
+
+// Player is one commander on a team. Teammates share towers and deploy zones
+// but each has their own hand and resource pool.
+type Player struct {
+	ID        string      `json:"id"`
+	Team      common.Team `json:"team"`
+	Hand      []string    `json:"hand"`
+	Resources float64     `json:"resources"`
+
+	deck []string // cards waiting to be drawn, next first
+}
+
+// DefaultDeck is every troop type, in name order.
+func DefaultDeck() []string {
+	deck := troops.AvailableTroopTypes()
+	sort.Strings(deck)
+	return deck
+}
+
+// CheckDeck reports whether a deck a player chose can be dealt: every card
+// is a known troop type, and none is in it twice.
+func CheckDeck(deck []string) error {
+	seen := make(map[string]bool, len(deck))
+	for _, card := range deck {
+		if _, ok := troops.TroopRegistry[card]; !ok {
+			return fmt.Errorf("%w: %s", ErrUnknownTroop, card)
+		}
+		if seen[card] {
+			return fmt.Errorf("%s is in the deck twice", card)
+		}
+		seen[card] = true
+	}
+	return nil
+}
+
+// CanPlay reports whether the troop type is in the player's hand. A player
+// with no cards can play nothing.
+func (p *Player) CanPlay(troopType string) bool {
+	return p.handIndex(troopType) >= 0
+}
+
+func (p *Player) handIndex(troopType string) int {
+	for i, card := range p.Hand {
+		if card == troopType {
+			return i
+		}
+	}
+	return -1
+}
+
+// cycle puts a played card at the bottom of the deck and draws the next one
+// into its place.
+func (p *Player) cycle(troopType string) {
+	i := p.handIndex(troopType)
+	if i < 0 {
+		return
+	}
+	p.deck = append(p.deck, troopType)
+	p.Hand[i] = p.deck[0]
+	p.deck = p.deck[1:]
+}
+
+// TroopCost is how many resources it takes to spawn a troop type, which is
+// the troop's card level.
+func TroopCost(troopType string) float64 {
+	return float64(troops.TroopLevel(troopType))
+}
+
+// AddPlayer seats a player on a team and deals them the first HandSize cards
+// of the deck. The server picks the deck; the rest of it is drawn as cards
+// are played. Adding a player that already exists returns the existing one
+// unchanged.
+func (b *Battle) AddPlayer(id string, team common.Team, deck []string) *Player {
+	if p, ok := b.Players[id]; ok {
+		return p
+	}
+	n := min(HandSize, len(deck))
+	p := &Player{
+		ID:        id,
+		Team:      team,
+		Hand:      append([]string{}, deck[:n]...),
+		Resources: StartingResources,
+		deck:      append([]string{}, deck[n:]...),
+	}
+	b.Players[id] = p
+	return p
+}
+
+// SpawnTroopAs spawns a troop on behalf of a player, checking their hand and
+// paying for it from their resource pool, then cycles the card. The troop is
+// tagged with the player as its owner.
+func (b *Battle) SpawnTroopAs(playerID string, pos common.Position, troopType string) (*troops.Troop, error) {
+	p, ok := b.Players[playerID]
+	if !ok {
+		return nil, ErrNotInBattle
+	}
+	if !p.CanPlay(troopType) {
+		return nil, fmt.Errorf("%w: %s", ErrNotInHand, troopType)
+	}
+	cost := TroopCost(troopType)
+	if p.Resources < cost {
+		return nil, ErrNotEnoughResources
+	}
+	t, err := b.spawnTroop(p.Team, p.ID, pos, troopType)
+	if err != nil {
+		return nil, err
+	}
+	p.Resources -= cost
+	p.cycle(troopType)
+	return t, nil
+}
+
+// regenResources refills every player's resource pool for dt seconds.
+func (b *Battle) regenResources(dt float64) {
+	for _, p := range b.Players {
+		p.Resources = min(p.Resources+ResourcesPerSecond*dt, MaxResources)
+	}
+}
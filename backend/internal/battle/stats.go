@@ -0,0 +1,44 @@
+package battle
+
+// Stats tallies per-player numbers from the event stream, for results screens
+// and leaderboards. Subscribe it with b.Subscribe(stats.Record).
+type Stats struct {
+	Spawned         map[string]int `json:"spawned"`
+	DamageDealt     map[string]int `json:"damageDealt"`
+	Kills           map[string]int `json:"kills"`
+	TowersDestroyed map[string]int `json:"towersDestroyed"`
+
+	lastHitBy map[int]string // entity ID -> owner of the last troop to hit it
+}
+
+func NewStats() *Stats {
+	return &Stats{
+		Spawned:         make(map[string]int),
+		DamageDealt:     make(map[string]int),
+		Kills:           make(map[string]int),
+		TowersDestroyed: make(map[string]int),
+		lastHitBy:       make(map[int]string),
+	}
+}
+
+// Record updates the tallies with one event. Damage done by towers has no
+// owner and is not counted.
+func (s *Stats) Record(e Event) {
+	switch e.Type {
+	case EventSpawn:
+		s.Spawned[e.Owner]++
+	case EventAttack:
+		if e.Owner != "" {
+			s.DamageDealt[e.Owner] += e.Amount
+			s.lastHitBy[e.TargetID] = e.Owner
+		}
+	case EventDeath:
+		if killer, ok := s.lastHitBy[e.EntityID]; ok {
+			s.Kills[killer]++
+		}
+	case EventTowerDestroyed:
+		if killer, ok := s.lastHitBy[e.EntityID]; ok {
+			s.TowersDestroyed[killer]++
+		}
+	}
+}
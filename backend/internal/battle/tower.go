@@ -0,0 +1,110 @@
+package battle
+
+import (
+	"cse-110-project-team-30/backend/internal/battle/common"
+	"cse-110-project-team-30/backend/internal/battle/maps"
+	"cse-110-project-team-30/backend/internal/battle/troops"
+)
+
+// Tower is a tower from the map definition bound to the castle entity
+// fighting in the arena.
+type Tower struct {
+	maps.Tower
+	Alive  bool
+	Entity troops.Entity
+}
+
+// spawnTowers places every tower of the map in the arena. Tower entities use
+// negative IDs so they never collide with spawned troops.
+func (b *Battle) spawnTowers(def *maps.Definition) {
+	for _, spec := range def.Towers {
+		var castle troops.Entity
+		if spec.Role == maps.TowerKing {
+			castle = troops.NewKingCastle(-spec.ID, spec.Owner, spec.Position(), def.TowerStats.King)
+		} else {
+			castle = troops.NewCastle(-spec.ID, spec.Owner, spec.Position(), def.TowerStats.Side)
+		}
+		tower := &Tower{Tower: spec, Alive: true, Entity: castle}
+		b.Towers = append(b.Towers, tower)
+		b.Arena.AddTroop(spec.X, spec.Y, castle.GetTroop())
+		b.Troops = append(b.Troops, castle)
+	}
+}
+
+// towerFor returns the tower backing the given troop, or nil if the troop is
+// not a tower.
+func (b *Battle) towerFor(t *troops.Troop) *Tower {
+	for _, tower := range b.Towers {
+		if tower.Entity.GetTroop() == t {
+			return tower
+		}
+	}
+	return nil
+}
+
+// destroyTower marks a tower as fallen. A side tower opens up its lane to the
+// enemy; losing the king tower eliminates its team. Towers that already fell,
+// e.g. with their team's king, change nothing.
+func (b *Battle) destroyTower(tower *Tower) {
+	if !tower.Alive {
+		return
+	}
+	b.emit(Event{Type: EventTowerDestroyed, EntityID: tower.Entity.GetTroop().ID, TowerID: tower.ID, Team: tower.Owner})
+	tower.Alive = false
+	if tower.Role != maps.TowerKing {
+		b.unlockDeployZones(tower)
+		return
+	}
+	b.eliminateTeam(tower.Owner)
+}
+
+// eliminateTeam takes a team out of the battle: its towers fall, its troops
+// die and it can no longer deploy. The game ends once one team is left.
+func (b *Battle) eliminateTeam(team common.Team) {
+	if b.Eliminated[team] {
+		return
+	}
+	b.Eliminated[team] = true
+	b.emit(Event{Type: EventTeamEliminated, Team: team})
+	for _, tower := range b.Towers {
+		if tower.Owner == team && tower.Alive {
+			tower.Alive = false
+			b.emit(Event{Type: EventTowerDestroyed, EntityID: tower.Entity.GetTroop().ID, TowerID: tower.ID, Team: team})
+		}
+	}
+	for _, e := range b.Troops {
+		if t := e.GetTroop(); t.Team == team {
+			t.Health = 0
+		}
+	}
+	delete(b.DeployZones, team)
+
+	remaining := b.RemainingTeams()
+	if len(remaining) <= 1 {
+		if len(remaining) == 1 {
+			b.Winner = &remaining[0]
+		}
+		b.EndGame()
+	}
+}
+
+// RemainingTeams lists the teams that have not been eliminated.
+func (b *Battle) RemainingTeams() []common.Team {
+	remaining := []common.Team{}
+	for _, team := range b.Teams {
+		if !b.Eliminated[team] {
+			remaining = append(remaining, team)
+		}
+	}
+	return remaining
+}
+
+// TowerStatus reports which towers are still standing, per team, in the
+// order the map lists them.
+func (b *Battle) TowerStatus() map[common.Team][]bool {
+	status := make(map[common.Team][]bool)
+	for _, tower := range b.Towers {
+		status[tower.Owner] = append(status[tower.Owner], tower.Alive)
+	}
+	return status
+}
@@ -4,22 +4,42 @@ import "cse-110-project-team-30/backend/internal/battle/common"
 
 // TroopRegistry maps string keys to constructor functions.
 var TroopRegistry = map[string]func(team common.Team, pos common.Position) Entity{
+	"ArcherFour": NewArcherFour,
+	"ArcherOne": NewArcherOne,
+	"ArcherThree": NewArcherThree,
 	"ArcherTwo": NewArcherTwo,
-	"SpearmanTwo": NewSpearmanTwo,
-	"SwordsmanTwo": NewSwordsmanTwo,
+	"CavalryFour": NewCavalryFour,
+	"CavalryOne": NewCavalryOne,
+	"CavalryThree": NewCavalryThree,
+	"CavalryTwo": NewCavalryTwo,
+	"SpearmanFour": NewSpearmanFour,
 	"SpearmanOne": NewSpearmanOne,
 	"SpearmanThree": NewSpearmanThree,
-	"SpearmanFour": NewSpearmanFour,
-	"CavalryTwo": NewCavalryTwo,
-	"CavalryThree": NewCavalryThree,
+	"SpearmanTwo": NewSpearmanTwo,
+	"SwordsmanFour": NewSwordsmanFour,
 	"SwordsmanOne": NewSwordsmanOne,
-	"ArcherOne": NewArcherOne,
-	"ArcherThree": NewArcherThree,
 	"SwordsmanThree": NewSwordsmanThree,
-	"SwordsmanFour": NewSwordsmanFour,
-	"ArcherFour": NewArcherFour,
-	"CavalryOne": NewCavalryOne,
-	"CavalryFour": NewCavalryFour,
+	"SwordsmanTwo": NewSwordsmanTwo,
+}
+
+// TroopLevels maps string keys to the troop's card level.
+var TroopLevels = map[string]int{
+	"ArcherFour": 4,
+	"ArcherOne": 1,
+	"ArcherThree": 3,
+	"ArcherTwo": 2,
+	"CavalryFour": 4,
+	"CavalryOne": 1,
+	"CavalryThree": 3,
+	"CavalryTwo": 2,
+	"SpearmanFour": 4,
+	"SpearmanOne": 1,
+	"SpearmanThree": 3,
+	"SpearmanTwo": 2,
+	"SwordsmanFour": 4,
+	"SwordsmanOne": 1,
+	"SwordsmanThree": 3,
+	"SwordsmanTwo": 2,
 }
 
 // NewTroopByType creates a new troop by its type string.
@@ -30,6 +50,11 @@ func NewTroopByType(troopType string, team common.Team, pos common.Position) Ent
 	return nil
 }
 
+// TroopLevel returns the level of a troop type, or 0 if the type is unknown.
+func TroopLevel(troopType string) int {
+	return TroopLevels[troopType]
+}
+
 // AvailableTroopTypes returns all keys in the registry
 func AvailableTroopTypes() []string {
 	keys := make([]string, 0, len(TroopRegistry))
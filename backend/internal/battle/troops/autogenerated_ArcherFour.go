@@ -15,7 +15,8 @@ func NewArcherFour(team common.Team, pos common.Position) Entity {
 			Type:     "ArcherFour",
 			Health:   32,
 			Damage:   5,
-			Speed:    1.2,
+			Speed:    6,
+			Cooldown: 0.2,
 			Range:    7,
 			Position: pos,
 			Team:     team,
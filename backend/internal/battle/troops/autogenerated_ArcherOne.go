@@ -15,7 +15,8 @@ func NewArcherOne(team common.Team, pos common.Position) Entity {
 			Type:     "ArcherOne",
 			Health:   20,
 			Damage:   4,
-			Speed:    1.2,
+			Speed:    6,
+			Cooldown: 0.2,
 			Range:    7,
 			Position: pos,
 			Team:     team,
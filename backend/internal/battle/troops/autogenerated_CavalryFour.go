@@ -15,7 +15,8 @@ func NewCavalryFour(team common.Team, pos common.Position) Entity {
 			Type:     "CavalryFour",
 			Health:   80,
 			Damage:   12,
-			Speed:    1.5,
+			Speed:    7.5,
+			Cooldown: 0.2,
 			Range:    1,
 			Position: pos,
 			Team:     team,
@@ -15,7 +15,8 @@ func NewCavalryOne(team common.Team, pos common.Position) Entity {
 			Type:     "CavalryOne",
 			Health:   28,
 			Damage:   10,
-			Speed:    1.5,
+			Speed:    7.5,
+			Cooldown: 0.2,
 			Range:    1,
 			Position: pos,
 			Team:     team,
@@ -15,7 +15,8 @@ func NewCavalryTwo(team common.Team, pos common.Position) Entity {
 			Type:     "CavalryTwo",
 			Health:   62,
 			Damage:   31,
-			Speed:    1.5,
+			Speed:    7.5,
+			Cooldown: 0.2,
 			Range:    1,
 			Position: pos,
 			Team:     team,
@@ -15,7 +15,8 @@ func NewSpearmanOne(team common.Team, pos common.Position) Entity {
 			Type:     "SpearmanOne",
 			Health:   28,
 			Damage:   5,
-			Speed:    1,
+			Speed:    5,
+			Cooldown: 0.2,
 			Range:    2,
 			Position: pos,
 			Team:     team,
@@ -15,7 +15,8 @@ func NewSpearmanThree(team common.Team, pos common.Position) Entity {
 			Type:     "SpearmanThree",
 			Health:   36,
 			Damage:   6,
-			Speed:    1,
+			Speed:    5,
+			Cooldown: 0.2,
 			Range:    2,
 			Position: pos,
 			Team:     team,
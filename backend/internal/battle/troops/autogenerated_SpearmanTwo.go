@@ -15,7 +15,8 @@ func NewSpearmanTwo(team common.Team, pos common.Position) Entity {
 			Type:     "SpearmanTwo",
 			Health:   32,
 			Damage:   5,
-			Speed:    1,
+			Speed:    5,
+			Cooldown: 0.2,
 			Range:    2,
 			Position: pos,
 			Team:     team,
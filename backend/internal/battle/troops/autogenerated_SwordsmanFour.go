@@ -15,7 +15,8 @@ func NewSwordsmanFour(team common.Team, pos common.Position) Entity {
 			Type:     "SwordsmanFour",
 			Health:   32,
 			Damage:   5,
-			Speed:    1,
+			Speed:    5,
+			Cooldown: 0.2,
 			Range:    1,
 			Position: pos,
 			Team:     team,
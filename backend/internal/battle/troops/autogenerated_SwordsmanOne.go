@@ -15,7 +15,8 @@ func NewSwordsmanOne(team common.Team, pos common.Position) Entity {
 			Type:     "SwordsmanOne",
 			Health:   20,
 			Damage:   4,
-			Speed:    1,
+			Speed:    5,
+			Cooldown: 0.2,
 			Range:    1,
 			Position: pos,
 			Team:     team,
@@ -15,7 +15,8 @@ func NewSwordsmanThree(team common.Team, pos common.Position) Entity {
 			Type:     "SwordsmanThree",
 			Health:   28,
 			Damage:   5,
-			Speed:    1,
+			Speed:    5,
+			Cooldown: 0.2,
 			Range:    1,
 			Position: pos,
 			Team:     team,
@@ -15,7 +15,8 @@ func NewSwordsmanTwo(team common.Team, pos common.Position) Entity {
 			Type:     "SwordsmanTwo",
 			Health:   24,
 			Damage:   4,
-			Speed:    1,
+			Speed:    5,
+			Cooldown: 0.2,
 			Range:    1,
 			Position: pos,
 			Team:     team,
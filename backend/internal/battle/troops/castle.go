@@ -9,32 +9,40 @@ type Castle struct {
 	Troop
 }
 
-func NewCastle(id int, team common.Team, pos common.Position) Entity {
-	return &Castle{
-		Troop: Troop{
-			ID:       id,
-			Type:     "Castle",
-			Team:     team,
-			Position: pos,
-			Health:   200,
-			Damage:   1,
-			Range:    10,
-			Speed:    0,
-		},
-	}
+// CastleStats holds the combat stats of a tower. Maps can override them per
+// tower role.
+type CastleStats struct {
+	Health int `json:"health"`
+	Damage int `json:"damage"`
+	Range  int `json:"range"`
+}
+
+// Default stats used when a map does not specify its own.
+var (
+	DefaultCastleStats     = CastleStats{Health: 200, Damage: 1, Range: 10}
+	DefaultKingCastleStats = CastleStats{Health: 300, Damage: 1, Range: 10}
+)
+
+func NewCastle(id int, team common.Team, pos common.Position, stats CastleStats) Entity {
+	return newCastle(id, "Castle", team, pos, stats)
+}
+
+func NewKingCastle(id int, team common.Team, pos common.Position, stats CastleStats) Entity {
+	return newCastle(id, "KingTower", team, pos, stats)
 }
 
-func NewKingCastle(id int, team common.Team, pos common.Position) Entity {
+func newCastle(id int, troopType string, team common.Team, pos common.Position, stats CastleStats) Entity {
 	return &Castle{
 		Troop: Troop{
 			ID:       id,
-			Type:     "KingTower",
+			Type:     troopType,
 			Team:     team,
 			Position: pos,
-			Health:   300,
-			Damage:   1,
-			Range:    10,
+			Health:   stats.Health,
+			Damage:   stats.Damage,
+			Range:    stats.Range,
 			Speed:    0,
+			Cooldown: DefaultCooldown,
 		},
 	}
 }
@@ -24,12 +24,22 @@ type Troop struct {
 	Type     string
 	Health   int
 	Team     common.Team     // e.g., 0 for player, 1 for enemy
+	Owner    string          // ID of the player who spawned it, empty for towers
 	Position common.Position // optional: x, y on the map
 	Damage   int
-	Speed    float64
+	Speed    float64 // tiles per second
+	Cooldown float64 // seconds between attacks
 	Range    int
+	Velocity common.Position // tiles per second it is walking at, for smoothing on the client
+	TargetID int             // entity it is attacking, 0 if none
+	Stride   float64         `json:"-"` // how far it has walked towards the next tile, in tiles
+	Reload   float64         `json:"-"` // seconds until it can attack again
 }
 
+// DefaultCooldown is the time between attacks for troops and towers that
+// don't set one: one attack per 200ms tick.
+const DefaultCooldown = 0.2
+
 // CalculateAction for a generic troop — warns if called
 func (t *Troop) CalculateAction(mv MapView) Action {
 	fmt.Printf("WARNING: CalculateAction called on base Troop (ID=%d, Type=%s). You should override this method.\n", t.ID, t.Type)
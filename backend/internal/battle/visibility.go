@@ -0,0 +1,79 @@
+package battle
+
+import (
+	"cse-110-project-team-30/backend/internal/battle/common"
+	"cse-110-project-team-30/backend/internal/battle/troops"
+)
+
+// sightSource is a circle of tiles a team can see.
+type sightSource struct {
+	center common.Position
+	radius float64
+}
+
+// Vision is what one team can see under fog of war.
+type Vision struct {
+	Team    common.Team
+	sources []sightSource
+	towers  map[*troops.Troop]bool // every tower's entity, whoever owns it
+}
+
+// HasFog reports whether the battle's map hides enemies out of sight.
+func (b *Battle) HasFog() bool {
+	return b.Map != nil && b.Map.Fog != nil
+}
+
+// VisionFor returns what the team can see right now, or nil if the team sees
+// everything: either the map has no fog, or the team is out of the game and
+// only watching.
+func (b *Battle) VisionFor(team common.Team) *Vision {
+	if !b.HasFog() || b.Eliminated[team] || !b.Enabled {
+		return nil
+	}
+	v := &Vision{Team: team, towers: make(map[*troops.Troop]bool, len(b.Towers))}
+	for _, tower := range b.Towers {
+		v.towers[tower.Entity.GetTroop()] = true
+		if tower.Owner == team && tower.Alive {
+			v.sources = append(v.sources, sightSource{tower.Position(), b.Map.Fog.TowerSight})
+		}
+	}
+	for _, e := range b.Troops {
+		t := e.GetTroop()
+		if t.Team == team && t.Health > 0 && !v.towers[t] { // towers are counted above
+			v.sources = append(v.sources, sightSource{t.Position, b.Map.Fog.TroopSight})
+		}
+	}
+	return v
+}
+
+// CanSee reports whether the tile at pos is within sight. A nil Vision sees
+// everything.
+func (v *Vision) CanSee(pos common.Position) bool {
+	if v == nil {
+		return true
+	}
+	for _, s := range v.sources {
+		dx, dy := pos.X-s.center.X, pos.Y-s.center.Y
+		if dx*dx+dy*dy <= s.radius*s.radius {
+			return true
+		}
+	}
+	return false
+}
+
+// Visible filters entities down to the ones the team can see: its own, every
+// tower (they never move and are on the map for everyone), and enemies in
+// sight.
+func (v *Vision) Visible(entities []troops.Entity) []troops.Entity {
+	if v == nil {
+		return entities
+	}
+	visible := make([]troops.Entity, 0, len(entities))
+	for _, e := range entities {
+		t := e.GetTroop()
+		if t.Team == v.Team || v.towers[t] || v.CanSee(t.Position) {
+			visible = append(visible, e)
+		}
+	}
+	return visible
+}
@@ -0,0 +1,57 @@
+// Package bot plays seats in a battle from the server. Strategies implement
+// Commander; a Bot drives one under the same rules as a human: it pays for
+// troops from its resource pool, plays only cards in its hand and deploys
+// only inside its team's deploy zones.
+package bot
+
+import (
+	"math/rand"
+
+	"cse-110-project-team-30/backend/internal/battle"
+	"cse-110-project-team-30/backend/internal/battle/common"
+)
+
+// Bot seats a Commander in a battle as a player.
+type Bot struct {
+	ID        string
+	Team      common.Team
+	Deck      []string // cards the bot draws from, dealt when it first acts
+	Commander Commander
+
+	subscribed bool
+}
+
+// New creates a bot playing for team with every troop type in its deck,
+// shuffled like a human player's. A commander that is a Shuffler shuffles
+// it with its own randomness.
+func New(id string, team common.Team, commander Commander) *Bot {
+	deck := battle.DefaultDeck()
+	if s, ok := commander.(Shuffler); ok {
+		s.Shuffle(deck)
+	} else {
+		rand.Shuffle(len(deck), func(i, j int) { deck[i], deck[j] = deck[j], deck[i] })
+	}
+	return &Bot{
+		ID:        id,
+		Team:      team,
+		Deck:      deck,
+		Commander: commander,
+	}
+}
+
+// Act asks the commander for its moves and spawns whatever the rules allow.
+// It should be called once per tick, from the goroutine that ticks the
+// battle.
+func (bt *Bot) Act(b *battle.Battle) {
+	p := b.AddPlayer(bt.ID, bt.Team, bt.Deck)
+	if l, ok := bt.Commander.(EventListener); ok && !bt.subscribed {
+		b.Subscribe(l.OnEvent)
+		bt.subscribed = true
+	}
+	if !b.Enabled || b.Eliminated[bt.Team] {
+		return
+	}
+	for _, cmd := range bt.Commander.Decide(b, p) {
+		b.SpawnTroopAs(bt.ID, cmd.Position, cmd.TroopType)
+	}
+}
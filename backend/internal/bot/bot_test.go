@@ -0,0 +1,113 @@
+package bot
+
+import (
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"cse-110-project-team-30/backend/internal/battle"
+	"cse-110-project-team-30/backend/internal/battle/common"
+)
+
+func TestBotSpawnsTroops(t *testing.T) {
+	b := battle.NewBattle()
+	bt := New("bot-1", common.TeamBlue, NewGreedy(1, time.Second))
+
+	for i := 0; i < 200 && b.Enabled; i++ {
+		b.Tick()
+		bt.Act(b)
+	}
+
+	spawned := 0
+	for _, e := range b.Troops {
+		troop := e.GetTroop()
+		if troop.Owner != "bot-1" {
+			continue
+		}
+		spawned++
+		if troop.Team != common.TeamBlue {
+			t.Errorf("bot troop on team %d, want blue", troop.Team)
+		}
+	}
+	if spawned == 0 {
+		t.Fatal("expected the bot to spawn troops")
+	}
+	if p := b.Players["bot-1"]; p.Resources < 0 {
+		t.Errorf("bot overspent its resources: %v", p.Resources)
+	}
+}
+
+// Bots get shuffled decks like humans, reproducible from the commander's
+// seed.
+func TestBotDecksAreShuffled(t *testing.T) {
+	first := New("bot-1", common.TeamRed, NewGreedy(7, time.Second)).Deck
+	again := New("bot-1", common.TeamRed, NewGreedy(7, time.Second)).Deck
+	other := New("bot-1", common.TeamRed, NewGreedy(8, time.Second)).Deck
+	if strings.Join(first, ",") != strings.Join(again, ",") {
+		t.Errorf("expected the same seed to deal the same deck, got %v and %v", first, again)
+	}
+	if strings.Join(first, ",") == strings.Join(other, ",") {
+		t.Errorf("expected another seed to shuffle differently, both got %v", first)
+	}
+	sorted := append([]string{}, first...)
+	sort.Strings(sorted)
+	if strings.Join(sorted, ",") != strings.Join(battle.DefaultDeck(), ",") {
+		t.Errorf("expected every troop type once, got %v", first)
+	}
+}
+
+// Bots decide as often in game time whatever the room's tick rate.
+func TestDecisionsFollowGameTime(t *testing.T) {
+	for _, tick := range []time.Duration{200 * time.Millisecond, 50 * time.Millisecond} {
+		b := battle.NewBattle()
+		b.TickDuration = tick
+		decisions := 0
+		for i := 0; i < int(10*time.Second/tick); i++ {
+			b.TickCount = i
+			if due(b, time.Second) {
+				decisions++
+			}
+		}
+		if decisions != 10 {
+			t.Errorf("%v ticks: expected 10 decisions in 10 seconds, got %d", tick, decisions)
+		}
+	}
+	b := battle.NewBattle()
+	b.TickCount = 7
+	if !due(b, time.Millisecond) {
+		t.Error("expected an interval shorter than a tick to mean every tick")
+	}
+}
+
+func TestTournamentPlaysEveryPairing(t *testing.T) {
+	ladder, err := RunTournament([]string{Easy, Normal, Hard}, TournamentOptions{
+		Map:      "classic",
+		Rounds:   2,
+		MaxTicks: 300,
+		Seed:     42,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ladder) != 3 {
+		t.Fatalf("expected 3 standings, got %d", len(ladder))
+	}
+	for _, s := range ladder {
+		// Each commander plays 2 rounds against each of the 2 others
+		if played := s.Wins + s.Losses + s.Draws; played != 4 {
+			t.Errorf("%s played %d matches, want 4", s.Name, played)
+		}
+	}
+	for i := 1; i < len(ladder); i++ {
+		if ladder[i].Points() > ladder[i-1].Points() {
+			t.Errorf("ladder is not sorted by points: %+v", ladder)
+		}
+	}
+}
+
+func TestUnknownCommander(t *testing.T) {
+	if _, err := RunTournament([]string{Easy, "nope"}, TournamentOptions{Map: "classic", Rounds: 1, MaxTicks: 10}); err == nil {
+		t.Error("expected an error for an unregistered commander")
+	}
+}
@@ -0,0 +1,79 @@
+package bot
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"cse-110-project-team-30/backend/internal/battle"
+	"cse-110-project-team-30/backend/internal/battle/common"
+)
+
+// SpawnCommand asks for a troop to be spawned for the commander's player.
+type SpawnCommand struct {
+	TroopType string
+	Position  common.Position
+}
+
+// Commander is a bot strategy. Decide is called once per tick with the battle
+// and the player the commander controls, and returns the spawns it wants.
+// Commands are checked against the same rules as human input, so a
+// commander only has to observe the battle, never modify it.
+type Commander interface {
+	Name() string
+	Decide(b *battle.Battle, p *battle.Player) []SpawnCommand
+}
+
+// EventListener can be implemented by a Commander that wants every battle
+// event, e.g. to keep track of what the opponent plays.
+type EventListener interface {
+	OnEvent(e battle.Event)
+}
+
+// Shuffler can be implemented by a Commander to shuffle its bot's deck with
+// its own seeded randomness, so that matches can be replayed.
+type Shuffler interface {
+	Shuffle(deck []string)
+}
+
+// Factory builds a commander. The seed makes its randomness reproducible.
+type Factory func(seed int64) Commander
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]Factory{}
+)
+
+// Register makes a commander available by name to matchmaking and the
+// ladder. Registering the same name twice panics.
+func Register(name string, factory Factory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if _, ok := registry[name]; ok {
+		panic(fmt.Sprintf("bot: commander %q registered twice", name))
+	}
+	registry[name] = factory
+}
+
+// NewCommander builds a registered commander.
+func NewCommander(name string, seed int64) (Commander, error) {
+	registryMu.RLock()
+	factory, ok := registry[name]
+	registryMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown commander %q", name)
+	}
+	return factory(seed), nil
+}
+
+// Commanders lists every registered commander name.
+func Commanders() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
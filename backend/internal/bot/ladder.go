@@ -0,0 +1,136 @@
+package bot
+
+import (
+	"fmt"
+	"sort"
+
+	"cse-110-project-team-30/backend/internal/battle"
+	"cse-110-project-team-30/backend/internal/battle/maps"
+)
+
+// Result of a single headless match.
+type Result int
+
+const (
+	Draw Result = iota
+	RedWins
+	BlueWins
+)
+
+// PlayMatch plays red against blue on a two-team map with no humans and no
+// clock, until a king falls or maxTicks pass. A match that times out goes to
+// the team with more towers standing.
+func PlayMatch(def *maps.Definition, red, blue Commander, maxTicks int) Result {
+	b := battle.NewBattleFromMap(def)
+	teams := b.Teams
+	if len(teams) != 2 {
+		panic(fmt.Sprintf("bot: map %s has %d teams, matches need 2", def.Name, len(teams)))
+	}
+	bots := []*Bot{
+		New("red", teams[0], red),
+		New("blue", teams[1], blue),
+	}
+	for b.Enabled && b.TickCount < maxTicks {
+		b.Tick()
+		for _, bt := range bots {
+			bt.Act(b)
+		}
+	}
+
+	if b.Winner != nil {
+		if *b.Winner == teams[0] {
+			return RedWins
+		}
+		return BlueWins
+	}
+	status := b.TowerStatus()
+	redTowers, blueTowers := countAlive(status[teams[0]]), countAlive(status[teams[1]])
+	switch {
+	case redTowers > blueTowers:
+		return RedWins
+	case blueTowers > redTowers:
+		return BlueWins
+	}
+	return Draw
+}
+
+func countAlive(towers []bool) int {
+	n := 0
+	for _, alive := range towers {
+		if alive {
+			n++
+		}
+	}
+	return n
+}
+
+// Standing is one commander's row in the ladder.
+type Standing struct {
+	Name   string
+	Wins   int
+	Losses int
+	Draws  int
+}
+
+// Points scores a standing: 3 for a win, 1 for a draw.
+func (s Standing) Points() int {
+	return 3*s.Wins + s.Draws
+}
+
+// TournamentOptions configures RunTournament.
+type TournamentOptions struct {
+	Map      string // two-team map to play on
+	Rounds   int    // matches per pairing; sides swap every match
+	MaxTicks int    // ticks before a match is called
+	Seed     int64
+}
+
+// RunTournament plays every pair of registered commanders against each
+// other, round-robin, and returns the ladder sorted by points.
+func RunTournament(names []string, opts TournamentOptions) ([]Standing, error) {
+	def, err := maps.Get(opts.Map)
+	if err != nil {
+		return nil, err
+	}
+	standings := make([]Standing, len(names))
+	for i, name := range names {
+		if _, err := NewCommander(name, 0); err != nil {
+			return nil, err
+		}
+		standings[i].Name = name
+	}
+
+	seed := opts.Seed
+	for i := range names {
+		for j := i + 1; j < len(names); j++ {
+			for round := 0; round < opts.Rounds; round++ {
+				// Swap sides every round so neither commander keeps the same half
+				a, c := i, j
+				if round%2 == 1 {
+					a, c = j, i
+				}
+				seed++
+				red, _ := NewCommander(names[a], seed)
+				blue, _ := NewCommander(names[c], seed+1<<32)
+				match, _ := maps.Get(def.Name)
+
+				switch PlayMatch(match, red, blue, opts.MaxTicks) {
+				case RedWins:
+					standings[a].Wins++
+					standings[c].Losses++
+				case BlueWins:
+					standings[c].Wins++
+					standings[a].Losses++
+				default:
+					standings[a].Draws++
+					standings[c].Draws++
+				}
+			}
+		}
+	}
+
+	sort.SliceStable(standings, func(i, j int) bool {
+		return standings[i].Points() > standings[j].Points()
+	})
+	return standings, nil
+}
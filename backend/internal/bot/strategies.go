@@ -0,0 +1,147 @@
+package bot
+
+import (
+	"math/rand"
+	"sort"
+	"time"
+
+	"cse-110-project-team-30/backend/internal/battle"
+	"cse-110-project-team-30/backend/internal/battle/common"
+	"cse-110-project-team-30/backend/internal/util"
+)
+
+// Difficulty levels offered to players. Each is a registered commander.
+const (
+	Easy   = "easy"
+	Normal = "normal"
+	Hard   = "hard"
+)
+
+func init() {
+	Register(Easy, func(seed int64) Commander { return NewRandom(seed, 2*time.Second) })
+	Register(Normal, func(seed int64) Commander { return NewGreedy(seed, time.Second) })
+	Register(Hard, func(seed int64) Commander { return NewGreedy(seed, 200*time.Millisecond) })
+}
+
+// due reports whether a commander deciding every interval of game time
+// should decide on this tick. An interval shorter than a tick means every
+// tick.
+func due(b *battle.Battle, interval time.Duration) bool {
+	ticks := max(1, int(interval/b.TickDuration))
+	return b.TickCount%ticks == 0
+}
+
+// Random plays a random card from its hand on a random free tile of its
+// deploy zones, every Interval of game time.
+type Random struct {
+	Interval time.Duration
+	rng      *rand.Rand
+}
+
+func NewRandom(seed int64, interval time.Duration) *Random {
+	return &Random{Interval: interval, rng: rand.New(rand.NewSource(seed))}
+}
+
+func (r *Random) Name() string { return "random" }
+
+func (r *Random) Shuffle(deck []string) { shuffle(r.rng, deck) }
+
+func (r *Random) Decide(b *battle.Battle, p *battle.Player) []SpawnCommand {
+	if !due(b, r.Interval) {
+		return nil
+	}
+	if len(p.Hand) == 0 {
+		return nil
+	}
+	card := p.Hand[r.rng.Intn(len(p.Hand))]
+	if p.Resources < battle.TroopCost(card) {
+		return nil
+	}
+	tiles := freeDeployTiles(b, p.Team)
+	if len(tiles) == 0 {
+		return nil
+	}
+	return []SpawnCommand{{TroopType: card, Position: tiles[r.rng.Intn(len(tiles))]}}
+}
+
+// Greedy picks a card, saves up for it, then deploys as close as it can to
+// the weakest enemy tower, with a little randomness so it isn't predictable.
+type Greedy struct {
+	Interval time.Duration // how often it decides, in game time
+	rng      *rand.Rand
+	next     string // card the commander is saving up for
+}
+
+func NewGreedy(seed int64, interval time.Duration) *Greedy {
+	return &Greedy{Interval: interval, rng: rand.New(rand.NewSource(seed))}
+}
+
+func (g *Greedy) Name() string { return "greedy" }
+
+func (g *Greedy) Shuffle(deck []string) { shuffle(g.rng, deck) }
+
+func (g *Greedy) Decide(b *battle.Battle, p *battle.Player) []SpawnCommand {
+	if !due(b, g.Interval) {
+		return nil
+	}
+
+	// Pick a card, then wait until it is affordable, like a human would
+	if g.next == "" {
+		if len(p.Hand) == 0 {
+			return nil
+		}
+		g.next = p.Hand[g.rng.Intn(len(p.Hand))]
+	}
+	if p.Resources < battle.TroopCost(g.next) {
+		return nil
+	}
+
+	target := weakestEnemyTower(b, p.Team)
+	tiles := freeDeployTiles(b, p.Team)
+	if target == nil || len(tiles) == 0 {
+		return nil
+	}
+	goal := target.Position()
+	sort.Slice(tiles, func(i, j int) bool {
+		return util.GetDistance(tiles[i], goal) < util.GetDistance(tiles[j], goal)
+	})
+
+	card := g.next
+	g.next = ""
+	return []SpawnCommand{{TroopType: card, Position: tiles[g.rng.Intn(min(5, len(tiles)))]}}
+}
+
+// shuffle puts a deck in random order.
+func shuffle(rng *rand.Rand, deck []string) {
+	rng.Shuffle(len(deck), func(i, j int) { deck[i], deck[j] = deck[j], deck[i] })
+}
+
+// freeDeployTiles lists every walkable, empty tile the team may deploy on.
+func freeDeployTiles(b *battle.Battle, team common.Team) []common.Position {
+	tiles := []common.Position{}
+	for _, zone := range b.DeployZones[team] {
+		for y := zone.Y; y < zone.Y+zone.H; y++ {
+			for x := zone.X; x < zone.X+zone.W; x++ {
+				pos := common.NewPosition(x, y)
+				if b.Arena.Walkable(pos) && len(b.Arena.Tiles[y][x].Troops) == 0 {
+					tiles = append(tiles, pos)
+				}
+			}
+		}
+	}
+	return tiles
+}
+
+// weakestEnemyTower returns the standing enemy tower with the least health.
+func weakestEnemyTower(b *battle.Battle, team common.Team) *battle.Tower {
+	var weakest *battle.Tower
+	for _, tower := range b.Towers {
+		if tower.Owner == team || !tower.Alive {
+			continue
+		}
+		if weakest == nil || tower.Entity.GetTroop().Health < weakest.Entity.GetTroop().Health {
+			weakest = tower
+		}
+	}
+	return weakest
+}
@@ -0,0 +1,54 @@
+package socket
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/gorilla/websocket"
+)
+
+// Encoding is the wire format of the messages a client receives. Both come
+// from the same message structs, so JSON stays around for debugging.
+type Encoding string
+
+const (
+	EncodingJSON    Encoding = "json"    // text frames, the default
+	EncodingMsgpack Encoding = "msgpack" // binary MessagePack frames
+)
+
+// ParseEncoding maps the name a client asks for in the auth message to an
+// encoding. An empty name means JSON.
+func ParseEncoding(s string) (Encoding, error) {
+	switch Encoding(s) {
+	case "", EncodingJSON:
+		return EncodingJSON, nil
+	case EncodingMsgpack:
+		return EncodingMsgpack, nil
+	}
+	return "", fmt.Errorf("unknown encoding %q", s)
+}
+
+// Marshal encodes a message in this encoding.
+func (e Encoding) Marshal(v any) ([]byte, error) {
+	if e == EncodingMsgpack {
+		return marshalMsgpack(v)
+	}
+	return json.Marshal(v)
+}
+
+// frameType is the websocket frame messages in this encoding are sent in.
+func (e Encoding) frameType() int {
+	if e == EncodingMsgpack {
+		return websocket.BinaryMessage
+	}
+	return websocket.TextMessage
+}
+
+// decodeFrame decodes a client message by its frame type, so clients can
+// send binary MessagePack or text JSON whatever they receive.
+func decodeFrame(frameType int, data []byte, v any) error {
+	if frameType == websocket.BinaryMessage {
+		return unmarshalMsgpack(data, v)
+	}
+	return json.Unmarshal(data, v)
+}
@@ -0,0 +1,60 @@
+package socket
+
+import (
+	"fmt"
+	"log"
+	"time"
+)
+
+// commandQueueSize is how many commands a room holds between ticks before
+// it turns new ones away with a busy error.
+const commandQueueSize = 256
+
+// command is a client message waiting for the room loop. Client readers
+// never touch the battle themselves: they check and queue commands, and the
+// loop runs everything queued at the start of each tick, in the order it
+// arrived, and sends each sender their reply.
+type command struct {
+	envelope
+	cl *client
+	at time.Time // when the message arrived
+}
+
+func newCommand(cl *client, env envelope) command {
+	return command{envelope: env, cl: cl, at: time.Now()}
+}
+
+// submit queues a client's message for the next tick, or replies with an
+// error right away if it can't be run.
+func (h *Hub) submit(cl *client, env envelope) {
+	if err := check(cl, env.Type); err != nil {
+		h.reject(cl, errorReply(env.ID, err))
+		return
+	}
+	select {
+	case h.cmdCh <- newCommand(cl, env):
+	default:
+		err := &requestError{code: codeBusy, err: fmt.Errorf("too many commands waiting")}
+		h.reject(cl, errorReply(env.ID, err))
+	}
+}
+
+// runCommands runs the commands queued before the tick started. Commands
+// that arrive meanwhile wait for the next tick.
+func (h *Hub) runCommands() {
+	for n := len(h.cmdCh); n > 0; n-- {
+		cmd := <-h.cmdCh
+		reply := h.dispatch(cmd)
+		if reply.Type == msgError {
+			h.reject(cmd.cl, reply)
+			continue
+		}
+		h.send(cmd.cl, reply)
+	}
+}
+
+// reject logs and sends an error reply.
+func (h *Hub) reject(cl *client, reply outbound) {
+	log.Println("client message rejected:", cl.UserID, reply.Payload)
+	h.send(cl, reply)
+}
@@ -0,0 +1,135 @@
+package socket
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"cse-110-project-team-30/backend/internal/battle"
+	"cse-110-project-team-30/backend/internal/battle/common"
+
+	"github.com/gorilla/websocket"
+)
+
+// TestCommandStress has many clients hammer a running room with commands
+// while it ticks. Run it with -race: every battle change has to happen on
+// the room loop.
+func TestCommandStress(t *testing.T) {
+	const clients, perClient = 16, 40
+
+	b := battle.NewBattle()
+	h := NewHub(b)
+	h.phase = PhaseRunning // skip the lobby
+	// Let every burst through
+	h.limits.ConnBurst, h.limits.UserBurst = perClient, perClient
+	h.SetDecks(func() []string { return []string{"SwordsmanOne"} })
+	go h.Run()
+	defer h.Stop()
+	if err := h.SetRates(time.Millisecond, 5*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+
+	var upgrader websocket.Upgrader
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		id := r.URL.Query().Get("user")
+		team := common.TeamRed
+		if strings.HasSuffix(id, "1") {
+			team = common.TeamBlue
+		}
+		h.AddClient(conn, ClientInfo{UserID: id, Seat: &Seat{UserID: id, Team: team}})
+	}))
+	defer srv.Close()
+
+	types := []string{"spawn", "pause", "resume", "resync", "spawn"}
+	var wg sync.WaitGroup
+	for i := 0; i < clients; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			url := "ws" + strings.TrimPrefix(srv.URL, "http") + fmt.Sprintf("?user=user-%d", i)
+			conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			defer conn.Close()
+
+			for n := 0; n < perClient; n++ {
+				msg := outbound{Type: types[n%len(types)], ID: fmt.Sprint(n)}
+				if msg.Type == "spawn" {
+					msg.Payload = spawnRequest{TroopType: "SwordsmanOne", X: n % 30, Y: 2 + i}
+				}
+				if err := conn.WriteJSON(msg); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+
+			// Every command gets exactly one reply
+			replies := map[string]bool{}
+			conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+			for len(replies) < perClient {
+				var reply struct {
+					Type string `json:"type"`
+					ID   string `json:"id"`
+				}
+				if err := conn.ReadJSON(&reply); err != nil {
+					t.Errorf("user-%d: got %d of %d replies: %v", i, len(replies), perClient, err)
+					return
+				}
+				if reply.Type != msgAck && reply.Type != msgError {
+					continue
+				}
+				if replies[reply.ID] {
+					t.Errorf("user-%d: request %s was answered twice", i, reply.ID)
+				}
+				replies[reply.ID] = true
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+// The room loop runs commands in the order they arrived and only at the
+// start of a tick.
+func TestCommandsWaitForTheTick(t *testing.T) {
+	b := battle.NewBattle()
+	h := NewHub(b)
+	h.phase = PhaseRunning // skip the lobby
+	b.AddPlayer("alice", common.TeamRed, []string{"SwordsmanOne"})
+	cl := &client{ClientInfo: ClientInfo{UserID: "alice"}}
+
+	owned := func() int {
+		n := 0
+		for _, e := range b.Troops {
+			if e.GetTroop().Owner == "alice" {
+				n++
+			}
+		}
+		return n
+	}
+
+	payload, _ := json.Marshal(spawnRequest{TroopType: "SwordsmanOne", X: 2, Y: 2})
+	h.submit(cl, envelope{Type: "spawn", ID: "1", Payload: payload})
+	h.submit(cl, envelope{Type: "pause", ID: "2"})
+	if owned() != 0 || b.Paused != nil {
+		t.Fatal("expected commands to wait for the room loop")
+	}
+
+	h.runCommands()
+	if spawned := owned(); spawned != 1 || b.Paused == nil {
+		t.Errorf("expected the spawn to land before the pause, got %d troops and pause %v", spawned, b.Paused)
+	}
+	if len(h.cmdCh) != 0 {
+		t.Errorf("expected the queue to be drained, %d left", len(h.cmdCh))
+	}
+}
@@ -0,0 +1,56 @@
+package socket
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Heartbeat is how a connection is kept alive. The server pings it every
+// Interval; one it hasn't heard from, pong or message, for Timeout is dead
+// and gets closed. Browsers answer pings on their own.
+type Heartbeat struct {
+	Interval time.Duration
+	Timeout  time.Duration
+}
+
+// DefaultHeartbeat is used for every websocket connection.
+var DefaultHeartbeat = Heartbeat{
+	Interval: 10 * time.Second,
+	Timeout:  30 * time.Second,
+}
+
+// KeepAlive starts pinging a connection and sets its read deadline, so the
+// next read fails once the connection goes quiet for Timeout. Pongs only
+// arrive while someone reads the connection. Each pong reports the round
+// trip time to onRTT, if it isn't nil, on the reading goroutine. Pinging
+// stops once the connection is closed.
+func (hb Heartbeat) KeepAlive(c *websocket.Conn, onRTT func(rtt time.Duration)) {
+	hb.Alive(c)
+	c.SetPongHandler(func(data string) error {
+		hb.Alive(c)
+		sent, err := strconv.ParseInt(data, 10, 64)
+		if err == nil && onRTT != nil {
+			onRTT(time.Since(time.Unix(0, sent)))
+		}
+		return nil
+	})
+	go func() {
+		ticker := time.NewTicker(hb.Interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			// The pong echoes the time, so the reader can tell how long it took
+			now := time.Now()
+			data := []byte(strconv.FormatInt(now.UnixNano(), 10))
+			if err := c.WriteControl(websocket.PingMessage, data, now.Add(writeWait)); err != nil {
+				return
+			}
+		}
+	}()
+}
+
+// Alive pushes back a connection's read deadline after hearing from it.
+func (hb Heartbeat) Alive(c *websocket.Conn) {
+	c.SetReadDeadline(time.Now().Add(hb.Timeout))
+}
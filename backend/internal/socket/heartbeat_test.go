@@ -0,0 +1,59 @@
+package socket
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHeartbeat(t *testing.T) {
+	hb := Heartbeat{Interval: 10 * time.Millisecond, Timeout: 100 * time.Millisecond}
+
+	// A peer that reads answers pings, and the server learns its RTT
+	conn, peer := serverConn(t)
+	rtts := make(chan time.Duration, 1)
+	hb.KeepAlive(conn, func(rtt time.Duration) {
+		select {
+		case rtts <- rtt:
+		default:
+		}
+	})
+	go func() {
+		for {
+			if _, _, err := peer.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+	errs := make(chan error, 1)
+	go func() {
+		_, _, err := conn.ReadMessage()
+		errs <- err
+	}()
+	select {
+	case rtt := <-rtts:
+		if rtt <= 0 || rtt > hb.Timeout {
+			t.Errorf("expected a small positive RTT, got %v", rtt)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected a pong")
+	}
+	select {
+	case err := <-errs:
+		t.Fatalf("expected a live connection to stay open, got %v", err)
+	case <-time.After(3 * hb.Timeout):
+	}
+	conn.Close()
+	<-errs
+
+	// A peer that never reads never answers, and the read times out
+	conn, _ = serverConn(t)
+	hb.KeepAlive(conn, nil)
+	start := time.Now()
+	if _, _, err := conn.ReadMessage(); err == nil {
+		t.Fatal("expected the read to fail on a quiet connection")
+	}
+	if waited := time.Since(start); waited < hb.Timeout || waited > 10*hb.Timeout {
+		t.Errorf("expected the connection to be given up after about %v, took %v", hb.Timeout, waited)
+	}
+	conn.Close()
+}
@@ -2,82 +2,256 @@
 package socket
 
 import (
-	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
+	"math/rand"
 	"sync"
 	"time"
 
 	"github.com/gorilla/websocket"
 
 	"cse-110-project-team-30/backend/internal/battle"
-	"cse-110-project-team-30/backend/internal/battle/common"
-	"cse-110-project-team-30/backend/internal/battle/troops"
+	"cse-110-project-team-30/backend/internal/bot"
 )
 
+// ClientInfo is what the hub knows about the user behind a connection.
+type ClientInfo struct {
+	UserID    string
+	Username  string
+	Deck      []string // cards the player chose to bring, dealt in order; empty for the room's default
+	Deltas    bool     // send keyframes and deltas instead of the full state every tick
+	Encoding  Encoding // wire format of state updates, JSON if empty
+	Seat      *Seat    // where the server seated the player, nil if they have no seat
+	Resumed   bool     // the player came back to a seat they already held
+	Spectator bool     // watches the whole battle, possibly delayed, and can't send commands
+}
+
+type client struct {
+	conn *websocket.Conn
+	ClientInfo
+	needsKeyframe bool    // the next update must be a full keyframe
+	view          viewKey // what the client was shown last tick
+
+	bucket   *tokenBucket // how many messages the connection may still send
+	drops    int          // messages dropped over the limits lately
+	lastDrop time.Time
+
+	out    chan frame // replies waiting for the client's writer
+	latest chan frame // the newest state update, if the writer hasn't sent it yet
+	missed int        // updates in a row replaced before the writer sent them
+}
+
+// frame is an encoded message on its way to a client.
+type frame struct {
+	kind int // websocket.TextMessage or websocket.BinaryMessage
+	data []byte
+}
+
+// Outbound limits. Every client has its own writer, so a slow one only
+// falls behind itself. It only ever has the newest state update waiting: one
+// the writer hasn't sent by the next broadcast is replaced by a keyframe, and
+// after maxMissed of those in a row the client is disconnected. Replies are
+// never dropped; a client that lets sendBuffer of them pile up is
+// disconnected.
+const (
+	sendBuffer = 64               // replies queued per client
+	maxMissed  = 25               // updates replaced in a row before a client is cut off
+	writeWait  = 10 * time.Second // how long one write may take
+)
+
+func newClient(c *websocket.Conn, info ClientInfo) *client {
+	return &client{
+		conn:          c,
+		ClientInfo:    info,
+		needsKeyframe: true,
+		out:           make(chan frame, sendBuffer),
+		latest:        make(chan frame, 1),
+	}
+}
+
+// DefaultSendInterval is how often clients get a state update, unless the
+// room sets its own rate.
+const DefaultSendInterval = 200 * time.Millisecond
+
+// ErrStopped is returned when a room that has stopped is asked to take a
+// connection or change its rates.
+var ErrStopped = errors.New("the room has stopped")
+
+// rates is how often a room ticks and sends updates.
+type rates struct {
+	tick, send time.Duration
+}
+
 type Hub struct {
 	mu      sync.Mutex
-	clients map[*websocket.Conn]bool
+	clients map[*websocket.Conn]*client
+	bots    []*bot.Bot
 	battle  *battle.Battle
-	addCh   chan *websocket.Conn
-	rmCh    chan *websocket.Conn
-	stopCh  chan struct{}
+	stats   *battle.Stats
+	deltas  map[viewKey]*deltaTracker
+
+	sendInterval time.Duration // how often clients get an update
+	sent         int           // updates broadcast so far, for periodic keyframes
+	heartbeat    Heartbeat     // how clients are pinged and when they count as gone
+	limits       Limits        // how fast clients may send messages
+
+	limitsMu sync.Mutex
+	users    map[string]*tokenBucket // message limits per user, by user ID
+
+	lobby    Lobby
+	phase    Phase
+	deadline time.Time       // when the current phase times out, or the countdown ends
+	ready    map[string]bool // seated players who confirmed they are ready, by user ID
+
+	idleSince time.Time // when the last player left or the hub was created; zero while a player is connected
+	joined    bool      // a player has connected at some point
+	endReason EndReason
+
+	deal func() []string // puts together each new player's deck
+
+	spectatorDelay time.Duration // how far spectators run behind the players
+	replay         []*stateView  // the spectator view of recent updates, oldest first
+
+	eventsMu sync.Mutex
+	events   []battle.Event // events since the last broadcast
+
+	addCh  chan *client
+	botCh  chan *bot.Bot
+	rmCh   chan *websocket.Conn
+	rateCh chan rates
+	cmdCh  chan command // client messages waiting for the next tick
+	stopCh chan struct{}
+
+	// Roster lists the seats that must be connected and ready before the
+	// battle starts. It is called with the hub's lock held.
+	Roster func() []Seat
+
+	// OnDisconnect is called with the user ID when a seated player's
+	// connection closes, with the hub's lock held.
+	OnDisconnect func(userID string)
 }
 
 func NewHub(b *battle.Battle) *Hub {
-	return &Hub{
-		clients: make(map[*websocket.Conn]bool),
+	h := &Hub{
+		clients: make(map[*websocket.Conn]*client),
 		battle:  b,
-		addCh:   make(chan *websocket.Conn),
+		stats:   battle.NewStats(),
+		deltas:  make(map[viewKey]*deltaTracker),
+		addCh:   make(chan *client),
+		botCh:   make(chan *bot.Bot),
 		rmCh:    make(chan *websocket.Conn),
+		rateCh:  make(chan rates),
+		cmdCh:   make(chan command, commandQueueSize),
 		stopCh:  make(chan struct{}),
+
+		sendInterval: DefaultSendInterval,
+		heartbeat:    DefaultHeartbeat,
+		limits:       DefaultLimits,
+		users:        make(map[string]*tokenBucket),
+		lobby:        DefaultLobby,
+		ready:        make(map[string]bool),
+		deal:         shuffledDeck,
+	}
+	h.idleSince = time.Now()
+	h.setPhaseLocked(PhaseWaiting, time.Now())
+	b.Subscribe(h.stats.Record)
+	b.Subscribe(h.queueEvent)
+	return h
+}
+
+// queueEvent buffers a battle event until the next broadcast.
+func (h *Hub) queueEvent(e battle.Event) {
+	h.eventsMu.Lock()
+	h.events = append(h.events, e)
+	h.eventsMu.Unlock()
+}
+
+// takeEvents returns the buffered events and clears the buffer. It never
+// returns nil, so updates always carry an events list.
+func (h *Hub) takeEvents() []battle.Event {
+	h.eventsMu.Lock()
+	defer h.eventsMu.Unlock()
+	events := h.events
+	h.events = nil
+	if events == nil {
+		events = []battle.Event{}
 	}
+	return events
 }
 
+// Run ticks the battle and sends updates to clients, each at its own rate,
+// until the hub is stopped. It is the only goroutine that changes the
+// battle: client commands are queued and run at the start of each tick. The
+// battle keeps a fixed timestep whenever the ticker fires, so a late wake-up
+// runs the ticks it missed.
 func (h *Hub) Run() {
-	ticker := time.NewTicker(200 * time.Millisecond)
-	defer ticker.Stop()
+	tick := time.NewTicker(h.battle.TickDuration)
+	defer tick.Stop()
+	send := time.NewTicker(h.sendInterval)
+	defer send.Stop()
+	last := time.Now()
 
 	for {
 		select {
-		case <-ticker.C:
-			// Step 1: advance the game
-			h.battle.Tick()
-
-			// Step 2: serialize arena
-			payload := struct {
-				Tick        int                    `json:"tick"`
-				Troops      []troops.Entity        `json:"troops"`
-				Ongoing     bool                   `json:"ongoing"`
-				TowerStatus map[common.Team][]bool `json:"towerStatus"`
-			}{
-				Tick:        h.battle.TickCount, // or whatever your tick variable is named
-				Troops:      h.battle.Troops,
-				Ongoing:     h.battle.Enabled,
-				TowerStatus: h.battle.TowerStatus,
-			}
-
-			state, err := json.Marshal(payload)
+		case now := <-tick.C:
+			// Step 1: run the commands clients sent since the last tick
+			h.runCommands()
 
-			if err != nil {
-				log.Println("error marshaling arena:", err)
-				continue
+			// Step 2: advance the game and let bots make their moves, once
+			// the room is running and unless it is paused
+			elapsed := now.Sub(last)
+			last = now
+			h.mu.Lock()
+			h.advanceLocked(now)
+			running := h.phase == PhaseRunning
+			h.mu.Unlock()
+			if running {
+				h.battle.CheckPause(now)
+				h.battle.Advance(elapsed, h.actBots)
 			}
 
-			// Step 3: broadcast to clients
-			h.broadcast(state)
+		case <-send.C:
+			// Step 3: send the new state to clients
+			h.broadcastState()
+
+		case r := <-h.rateCh:
+			tick.Reset(r.tick)
+			send.Reset(r.send)
+			h.mu.Lock()
+			h.battle.TickDuration = r.tick
+			h.sendInterval = r.send
+			h.resetReplayLocked() // the delay is counted in updates
+			h.mu.Unlock()
 
 		case c := <-h.addCh:
 			h.mu.Lock()
-			h.clients[c] = true
+			if c.Seat != nil {
+				deck := c.Deck
+				if len(deck) == 0 {
+					deck = h.deal()
+				}
+				h.battle.AddPlayer(c.UserID, c.Seat.Team, deck)
+			}
+			h.replaceStaleLocked(c)
+			h.clients[c.conn] = c
+			if c.Seat != nil {
+				h.idleSince = time.Time{}
+				h.joined = true
+			}
+			h.advanceLocked(time.Now())
 			h.mu.Unlock()
+			h.welcome(c)
+			go h.writeClient(c)
 			go h.handleClient(c)
 
+		case bt := <-h.botCh:
+			h.bots = append(h.bots, bt)
+
 		case c := <-h.rmCh:
 			h.mu.Lock()
-			if _, ok := h.clients[c]; ok {
-				c.Close()
-				delete(h.clients, c)
-			}
+			h.dropLocked(c)
+			h.advanceLocked(time.Now())
 			h.mu.Unlock()
 
 		case <-h.stopCh:
@@ -87,74 +261,402 @@ func (h *Hub) Run() {
 	}
 }
 
-func (h *Hub) AddClient(c *websocket.Conn) {
-	h.addCh <- c
+// actBots lets every bot make its move for the tick.
+func (h *Hub) actBots() {
+	for _, bt := range h.bots {
+		bt.Act(h.battle)
+	}
+}
+
+// AddClient hands a connection to the room. Once the hub has stopped, the
+// connection is closed instead and ErrStopped returned.
+func (h *Hub) AddClient(c *websocket.Conn, info ClientInfo) error {
+	select {
+	case h.addCh <- newClient(c, info):
+		return nil
+	case <-h.stopCh:
+		c.Close()
+		return ErrStopped
+	}
+}
+
+// AddBot seats a server-side bot in the room. The bot acts once per tick.
+func (h *Hub) AddBot(bt *bot.Bot) {
+	select {
+	case h.botCh <- bt:
+	case <-h.stopCh:
+	}
 }
 
+// RemoveClient takes a connection out of the room. It returns right away
+// once the hub has stopped, since Stop closed every connection already.
 func (h *Hub) RemoveClient(c *websocket.Conn) {
-	h.rmCh <- c
+	select {
+	case h.rmCh <- c:
+	case <-h.stopCh:
+	}
+}
+
+// SetRates sets how long a tick lasts and how often clients get an update,
+// for example 50ms and 100ms to simulate at 20Hz and send at 10Hz. Troops
+// move and attack in seconds, so the battle plays the same at any tick rate,
+// except that a troop moves at most one tile per tick. The hub must be
+// running; once it has stopped, SetRates fails.
+func (h *Hub) SetRates(tick, send time.Duration) error {
+	if err := CheckRates(tick, send); err != nil {
+		return err
+	}
+	select {
+	case h.rateCh <- rates{tick: tick, send: send}:
+		return nil
+	case <-h.stopCh:
+		return ErrStopped
+	}
+}
+
+// CheckRates reports whether a tick and send interval can be used.
+func CheckRates(tick, send time.Duration) error {
+	if tick <= 0 || send <= 0 {
+		return fmt.Errorf("tick and send intervals must be positive, got %v and %v", tick, send)
+	}
+	return nil
+}
+
+// SetDecks changes how the deck of a new player who didn't choose one is
+// put together. By default it is every troop type, shuffled.
+func (h *Hub) SetDecks(deal func() []string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.deal = deal
+}
+
+// shuffledDeck is every troop type in random order.
+func shuffledDeck() []string {
+	deck := battle.DefaultDeck()
+	rand.Shuffle(len(deck), func(i, j int) { deck[i], deck[j] = deck[j], deck[i] })
+	return deck
+}
+
+// SetSpectatorDelay makes spectators see the battle d behind the players,
+// so nobody can watch a fog of war match and tell a player what they can't
+// see.
+func (h *Hub) SetSpectatorDelay(d time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.spectatorDelay = d
+	h.resetReplayLocked()
+}
+
+// resetReplayLocked starts the spectators' replay over. h.mu must be held.
+func (h *Hub) resetReplayLocked() {
+	h.replay = nil
+	for _, cl := range h.clients {
+		if cl.Spectator {
+			cl.needsKeyframe = true
+		}
+	}
+}
+
+// Spectators counts the connected spectators.
+func (h *Hub) Spectators() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	n := 0
+	for _, cl := range h.clients {
+		if cl.Spectator {
+			n++
+		}
+	}
+	return n
 }
 
 func (h *Hub) Stop() {
 	close(h.stopCh)
 }
 
-func (h *Hub) broadcast(msg []byte) {
+// broadcastState sends the latest state to every client: the full state to
+// clients that didn't ask for deltas, and keyframes or deltas to those that
+// did. Under fog each team gets its own view, and spectators get the whole
+// battle as it was SpectatorDelay ago. Each view is built once per update and
+// each message encoded at most once per encoding.
+func (h *Hub) broadcastState() {
+	shared := h.snapshot(h.takeEvents())
+	h.sent++
+	periodic := h.sent%keyframeInterval == 0
+	views := map[viewKey]*stateView{}
+
 	h.mu.Lock()
 	defer h.mu.Unlock()
 
-	for c := range h.clients {
-		if err := c.WriteMessage(websocket.TextMessage, msg); err != nil {
-			c.Close()
-			delete(h.clients, c)
+	for _, cl := range h.clients {
+		key := h.viewFor(cl)
+		view, ok := views[key]
+		if !ok {
+			view = h.newView(key, shared)
+			if key.spectator {
+				view = h.delayForSpectators(view)
+			}
+			views[key] = view
+		}
+		if view == nil {
+			continue // spectators get nothing until the delay has passed
+		}
+		if key != cl.view {
+			// Deltas only make sense against the view the client had
+			cl.view = key
+			cl.needsKeyframe = true
+		}
+		if h.missedLocked(cl) {
+			if cl.missed >= maxMissed {
+				h.cutOffLocked(cl, "too slow to keep up with updates")
+				continue
+			}
+			cl.needsKeyframe = true // it never got the last delta
+		}
+
+		enc := cl.encoding()
+		var msg []byte
+		if !cl.Deltas || cl.needsKeyframe || periodic {
+			msg = view.encode(false, enc)
+			cl.needsKeyframe = false
+		} else {
+			msg = view.encode(true, enc)
+		}
+		if msg == nil {
+			continue
+		}
+		cl.latest <- frame{kind: enc.frameType(), data: msg}
+	}
+
+	// A view nobody watched this update has a stale baseline; start over
+	for key := range h.deltas {
+		if _, ok := views[key]; !ok {
+			delete(h.deltas, key)
 		}
 	}
+	if _, ok := views[viewKey{spectator: true}]; !ok {
+		h.replay = nil
+	}
+}
+
+// delayForSpectators holds on to this update's spectator view and returns the
+// one from SpectatorDelay ago, or nil if the battle hasn't been watched for
+// that long yet. h.mu must be held.
+func (h *Hub) delayForSpectators(view *stateView) *stateView {
+	if h.spectatorDelay > 0 {
+		view.freeze()
+	}
+	h.replay = append(h.replay, view)
+	behind := int(h.spectatorDelay / h.sendInterval)
+	if extra := len(h.replay) - behind - 1; extra > 0 {
+		h.replay = h.replay[extra:]
+	}
+	return h.spectatorView()
+}
+
+// spectatorView is what spectators are shown right now, or nil if there is
+// nothing old enough yet. h.mu must be held.
+func (h *Hub) spectatorView() *stateView {
+	behind := int(h.spectatorDelay / h.sendInterval)
+	if len(h.replay) <= behind {
+		return nil
+	}
+	return h.replay[0]
+}
+
+// requestResync makes the client's next update a keyframe.
+func (h *Hub) requestResync(cl *client) {
+	h.mu.Lock()
+	cl.needsKeyframe = true
+	h.mu.Unlock()
 }
 
 // --------------------
 // Client reader
 // --------------------
-func (h *Hub) handleClient(c *websocket.Conn) {
+func (h *Hub) handleClient(cl *client) {
+	c := cl.conn
 	defer h.RemoveClient(c)
+	h.heartbeat.KeepAlive(c, func(rtt time.Duration) {
+		h.send(cl, outbound{Type: msgLatency, Payload: latencyPayload{RTT: rtt.Milliseconds()}})
+	})
 
 	for {
-		_, msg, err := c.ReadMessage()
+		frameType, msg, err := c.ReadMessage()
 		if err != nil {
+			NoteReadError(cl.UserID, err)
 			break
 		}
-
-		var req struct {
-			TroopType string `json:"troopType"`
-			Team      string `json:"team"`
-			X         int    `json:"x"`
-			Y         int    `json:"y"`
+		h.heartbeat.Alive(c)
+		if now := time.Now(); !h.allow(cl, now) {
+			if h.overLimit(cl, now) {
+				break
+			}
+			continue
 		}
 
-		if err := json.Unmarshal(msg, &req); err != nil {
-			log.Println("invalid client message:", err)
+		env, err := decodeEnvelope(frameType, msg)
+		if err != nil {
+			h.reject(cl, errorReply("", err))
 			continue
 		}
+		h.submit(cl, env)
+	}
+}
+
+// send queues a reply for one client in the client's encoding.
+func (h *Hub) send(cl *client, msg outbound) {
+	data, err := cl.encoding().Marshal(msg)
+	if err != nil {
+		log.Println("error marshaling reply:", err)
+		return
+	}
+	h.sendEncoded(cl, data)
+}
+
+// sendEncoded queues a message already in the client's encoding.
+func (h *Hub) sendEncoded(cl *client, data []byte) {
+	if data == nil {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.clients[cl.conn]; !ok {
+		return
+	}
+	select {
+	case cl.out <- frame{kind: cl.encoding().frameType(), data: data}:
+	default:
+		h.cutOffLocked(cl, "too many replies waiting")
+	}
+}
+
+// missedLocked takes back the client's last update if the writer hasn't
+// sent it yet, and keeps count of how many in a row it missed. h.mu must be
+// held.
+func (h *Hub) missedLocked(cl *client) bool {
+	select {
+	case <-cl.latest:
+		cl.missed++
+		return true
+	default:
+		cl.missed = 0
+		return false
+	}
+}
+
+// cutOffLocked disconnects a client that can't keep up. h.mu must be held.
+func (h *Hub) cutOffLocked(cl *client, reason string) {
+	log.Println("disconnecting", cl.UserID+":", reason)
+	cl.conn.Close() // a writer stuck on a dead connection gives up now
+	h.dropLocked(cl.conn)
+}
 
-		team := parseTeam(req.Team)
-		pos := common.NewPosition(req.X, req.Y)
-		_, err2 := h.battle.SpawnTroop(team, pos, req.TroopType)
-		if err2 != nil {
-			log.Println("spawn error:", err2)
+// --------------------
+// Client writer
+// --------------------
+// writeClient sends the client's replies and updates until the hub drops
+// it, then sends the last update and closes the connection. A write that
+// fails or takes longer than writeWait closes the connection too, and the
+// reader removes the client.
+func (h *Hub) writeClient(cl *client) {
+	c := cl.conn
+	defer c.Close()
+
+	write := func(f frame) bool {
+		c.SetWriteDeadline(time.Now().Add(writeWait))
+		return c.WriteMessage(f.kind, f.data) == nil
+	}
+	for {
+		select {
+		case f, ok := <-cl.out:
+			if !ok {
+				select {
+				case f := <-cl.latest:
+					write(f)
+				default:
+				}
+				write(frame{kind: websocket.CloseMessage, data: websocket.FormatCloseMessage(websocket.CloseNormalClosure, "")})
+				return
+			}
+			if !write(f) {
+				return
+			}
+		case f := <-cl.latest:
+			if !write(f) {
+				return
+			}
 		}
 	}
 }
 
+// encoding is the client's wire format, JSON unless they asked otherwise.
+func (cl *client) encoding() Encoding {
+	if cl.Encoding == "" {
+		return EncodingJSON
+	}
+	return cl.Encoding
+}
+
 // --------------------
 // Helpers
 // --------------------
-func parseTeam(s string) common.Team {
-	switch s {
-	case "red":
-		return common.TeamRed
-	case "blue":
-		return common.TeamBlue
-	default:
-		return common.TeamRed
+// welcome greets a new connection with its seat and the full state, so a
+// player who reconnects can pick up right away instead of waiting a tick.
+// Spectators get the delayed state, once there is one.
+func (h *Hub) welcome(cl *client) {
+	joined := joinedPayload{Seat: cl.Seat, Resumed: cl.Resumed, Spectator: cl.Spectator}
+	if cl.Seat != nil {
+		joined.Player = h.battle.Players[cl.UserID]
+	}
+	h.send(cl, outbound{Type: msgJoined, Payload: joined})
+
+	h.mu.Lock()
+	delayed := cl.Spectator && h.spectatorDelay > 0
+	view := h.spectatorView()
+	h.mu.Unlock()
+	switch {
+	case !delayed:
+		h.send(cl, outbound{Type: msgState, Payload: h.keyframeFor(cl)})
+	case view != nil:
+		h.sendEncoded(cl, view.encode(false, cl.encoding()))
+	}
+}
+
+// dropLocked forgets a connection. Its writer sends what is still queued and
+// then closes it. h.mu must be held.
+func (h *Hub) dropLocked(c *websocket.Conn) {
+	cl, ok := h.clients[c]
+	if !ok {
+		return
+	}
+	close(cl.out)
+	delete(h.clients, c)
+	if cl.Seat == nil {
+		return
+	}
+	if h.OnDisconnect != nil {
+		h.OnDisconnect(cl.UserID)
+	}
+	for _, other := range h.clients {
+		if other.Seat != nil {
+			return
+		}
+	}
+	h.idleSince = time.Now()
+}
+
+// replaceStaleLocked closes a seated player's older connections when they
+// come back, since a dropped socket can linger until a write fails. h.mu
+// must be held.
+func (h *Hub) replaceStaleLocked(cl *client) {
+	if cl.Seat == nil {
+		return
+	}
+	for c, other := range h.clients {
+		if other.Seat != nil && other.UserID == cl.UserID {
+			h.dropLocked(c)
+		}
 	}
 }
 
@@ -163,7 +665,6 @@ func (h *Hub) closeAll() {
 	defer h.mu.Unlock()
 
 	for c := range h.clients {
-		c.Close()
-		delete(h.clients, c)
+		h.dropLocked(c)
 	}
 }
@@ -0,0 +1,104 @@
+package socket
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"cse-110-project-team-30/backend/internal/battle"
+
+	"github.com/gorilla/websocket"
+)
+
+// serverConn dials a test server and returns both ends of the connection:
+// the server's and the peer's.
+func serverConn(t *testing.T) (server, peer *websocket.Conn) {
+	conns := make(chan *websocket.Conn, 1)
+	var upgrader websocket.Upgrader
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		conns <- conn
+	}))
+	t.Cleanup(srv.Close)
+	peer, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { peer.Close() })
+	return <-conns, peer
+}
+
+func TestSlowClient(t *testing.T) {
+	h := NewHub(battle.NewBattle())
+	conn, _ := serverConn(t)
+	// No writer: the client never takes anything off its queues
+	cl := newClient(conn, ClientInfo{UserID: "alice", Deltas: true})
+	h.clients[conn] = cl
+
+	h.broadcastState()
+	for i := 1; i < maxMissed; i++ {
+		h.broadcastState()
+		if cl.missed != i {
+			t.Fatalf("update %d: expected %d missed updates, got %d", i, i, cl.missed)
+		}
+		if len(cl.latest) != 1 {
+			t.Fatalf("update %d: expected only the newest update to wait", i)
+		}
+	}
+
+	// The update that replaced a missed one is a keyframe, since the client
+	// never got the delta before it
+	var msg struct {
+		Payload struct {
+			Kind string `json:"kind"`
+		} `json:"payload"`
+	}
+	f := <-cl.latest
+	if err := json.Unmarshal(f.data, &msg); err != nil {
+		t.Fatal(err)
+	}
+	if msg.Payload.Kind != "keyframe" {
+		t.Errorf("expected a keyframe after a missed update, got %q", msg.Payload.Kind)
+	}
+	cl.latest <- f
+
+	h.broadcastState()
+	if _, ok := h.clients[conn]; ok {
+		t.Errorf("expected the client to be cut off after %d missed updates", maxMissed)
+	}
+}
+
+// Readers still remove their clients after the reaper stops a room, and late
+// connections still arrive. Neither may block on the stopped loop.
+func TestStoppedHub(t *testing.T) {
+	h := NewHub(battle.NewBattle())
+	go h.Run()
+	conn, _ := serverConn(t)
+	h.AddClient(conn, ClientInfo{UserID: "alice"})
+	h.Stop()
+
+	late, latePeer := serverConn(t)
+	done := make(chan struct{})
+	go func() {
+		h.RemoveClient(conn)
+		h.AddClient(late, ClientInfo{UserID: "bob"})
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("expected removing and adding clients on a stopped hub to return")
+	}
+
+	latePeer.SetReadDeadline(time.Now().Add(time.Second))
+	if _, _, err := latePeer.ReadMessage(); !websocket.IsCloseError(err, websocket.CloseAbnormalClosure) {
+		t.Errorf("expected a late connection to be closed, got %v", err)
+	}
+}
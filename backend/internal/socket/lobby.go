@@ -0,0 +1,225 @@
+package socket
+
+import (
+	"log"
+	"time"
+)
+
+// Phase is where a room is in its life. Rooms go waiting → readyCheck →
+// countdown → running → finished, and can be cancelled to finished from any
+// phase before running.
+type Phase string
+
+const (
+	PhaseWaiting    Phase = "waiting"    // waiting for every seat to connect
+	PhaseReadyCheck Phase = "readyCheck" // everyone is here; waiting for each to confirm
+	PhaseCountdown  Phase = "countdown"  // everyone is ready; the battle is about to start
+	PhaseRunning    Phase = "running"    // the battle is ticking
+	PhaseFinished   Phase = "finished"   // the battle is over or the room was cancelled
+)
+
+// EndReason says why a room finished.
+type EndReason string
+
+const (
+	EndFinished  EndReason = "finished"  // the battle was won, drawn or forfeited
+	EndCancelled EndReason = "cancelled" // not every player joined or was ready in time
+	EndAbandoned EndReason = "abandoned" // every player left and nobody came back
+	EndEmpty     EndReason = "empty"     // nobody ever joined
+)
+
+// Lobby is how long a room waits in each phase before the battle.
+type Lobby struct {
+	JoinTimeout  time.Duration // for every seat to connect, or the room is cancelled
+	ReadyTimeout time.Duration // for everyone to confirm, or the room is cancelled
+	Countdown    time.Duration // from everyone ready to the first tick
+}
+
+// DefaultLobby gives players as long to join as their match ticket lasts.
+var DefaultLobby = Lobby{
+	JoinTimeout:  time.Minute,
+	ReadyTimeout: 30 * time.Second,
+	Countdown:    3 * time.Second,
+}
+
+// SetLobby changes the room's lobby timings. The current phase's deadline
+// starts over.
+func (h *Hub) SetLobby(lobby Lobby) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.lobby = lobby
+	h.setPhaseLocked(h.phase, time.Now())
+}
+
+// Phase is where the room is in its life.
+func (h *Hub) Phase() Phase {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.phase
+}
+
+// beforeStart reports whether the battle is yet to start.
+func (h *Hub) beforeStart() bool {
+	switch h.Phase() {
+	case PhaseWaiting, PhaseReadyCheck, PhaseCountdown:
+		return true
+	}
+	return false
+}
+
+// setReady records that a seated player confirmed they are ready. It does
+// nothing once the countdown has started.
+func (h *Hub) setReady(userID string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.phase != PhaseWaiting && h.phase != PhaseReadyCheck {
+		return
+	}
+	h.ready[userID] = true
+	h.advanceLocked(time.Now())
+}
+
+// advanceLocked moves the room on to the next phase as long as it can, and
+// cancels it if a phase timed out. h.mu must be held.
+func (h *Hub) advanceLocked(now time.Time) {
+	if !h.battle.Enabled && h.phase != PhaseFinished {
+		h.setPhaseLocked(PhaseFinished, now)
+		h.endReason = EndFinished
+		return
+	}
+	for {
+		switch h.phase {
+		case PhaseWaiting:
+			switch {
+			case h.allConnectedLocked():
+				h.setPhaseLocked(PhaseReadyCheck, now)
+				continue
+			case now.After(h.deadline):
+				h.cancelLocked("not every player joined")
+			}
+		case PhaseReadyCheck:
+			switch {
+			case !h.allConnectedLocked():
+				h.setPhaseLocked(PhaseWaiting, now)
+			case h.allReadyLocked():
+				h.setPhaseLocked(PhaseCountdown, now)
+				continue
+			case now.After(h.deadline):
+				h.cancelLocked("not every player was ready")
+			}
+		case PhaseCountdown:
+			if !now.Before(h.deadline) {
+				h.setPhaseLocked(PhaseRunning, now)
+			}
+		}
+		return
+	}
+}
+
+// setPhaseLocked enters a phase and sets when it times out. h.mu must be
+// held.
+func (h *Hub) setPhaseLocked(phase Phase, now time.Time) {
+	h.phase = phase
+	switch phase {
+	case PhaseWaiting:
+		h.deadline = now.Add(h.lobby.JoinTimeout)
+	case PhaseReadyCheck:
+		h.deadline = now.Add(h.lobby.ReadyTimeout)
+	case PhaseCountdown:
+		h.deadline = now.Add(h.lobby.Countdown)
+	default:
+		h.deadline = time.Time{}
+	}
+}
+
+// cancelLocked gives up on a room that never got going. Ending the battle
+// tells the clients and has the manager delete the room. h.mu must be held.
+func (h *Hub) cancelLocked(reason string) {
+	log.Println("room cancelled:", reason)
+	h.setPhaseLocked(PhaseFinished, time.Time{})
+	h.endReason = EndCancelled
+	h.battle.EndGame()
+}
+
+// end marks the room finished for a reason other than the battle ending,
+// before it is deleted.
+func (h *Hub) end(reason EndReason) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.setPhaseLocked(PhaseFinished, time.Time{})
+	if h.endReason == "" {
+		h.endReason = reason
+	}
+}
+
+// EndReason says why the room finished, or is empty while it hasn't.
+func (h *Hub) EndReason() EndReason {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.endReason
+}
+
+// Idle reports how long the room has gone without a player connected, and
+// whether a player ever joined.
+func (h *Hub) Idle(now time.Time) (idle time.Duration, joined bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.idleSince.IsZero() {
+		return 0, true
+	}
+	return now.Sub(h.idleSince), h.joined
+}
+
+// allConnectedLocked reports whether every seat on the roster has a
+// connection. h.mu must be held.
+func (h *Hub) allConnectedLocked() bool {
+	seats := h.roster()
+	if len(seats) == 0 {
+		return false
+	}
+	connected := make(map[string]bool)
+	for _, cl := range h.clients {
+		if cl.Seat != nil {
+			connected[cl.UserID] = true
+		}
+	}
+	for _, s := range seats {
+		if !connected[s.UserID] {
+			return false
+		}
+	}
+	return true
+}
+
+// allReadyLocked reports whether every seat on the roster confirmed. Bots
+// are never on the roster and are always ready. h.mu must be held.
+func (h *Hub) allReadyLocked() bool {
+	for _, s := range h.roster() {
+		if !h.ready[s.UserID] {
+			return false
+		}
+	}
+	return true
+}
+
+// roster lists the seats that must be filled before the battle starts.
+func (h *Hub) roster() []Seat {
+	if h.Roster == nil {
+		return nil
+	}
+	return h.Roster()
+}
+
+// lobbyState is the room's phase as clients see it.
+func (h *Hub) lobbyState() (phase Phase, ends int64, ready map[string]bool, reason EndReason) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if !h.deadline.IsZero() {
+		ends = h.deadline.UnixMilli()
+	}
+	ready = make(map[string]bool, len(h.ready))
+	for id, ok := range h.ready {
+		ready[id] = ok
+	}
+	return h.phase, ends, ready, h.endReason
+}
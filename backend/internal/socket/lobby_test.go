@@ -0,0 +1,73 @@
+package socket
+
+import (
+	"testing"
+	"time"
+
+	"cse-110-project-team-30/backend/internal/battle"
+	"cse-110-project-team-30/backend/internal/battle/common"
+
+	"github.com/gorilla/websocket"
+)
+
+// connect adds a seated client to the hub the way Run does, without a
+// real connection.
+func connect(h *Hub, seat Seat) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.clients[&websocket.Conn{}] = newClient(nil, ClientInfo{UserID: seat.UserID, Seat: &seat})
+}
+
+func TestLobbyPhases(t *testing.T) {
+	b := battle.NewBattle()
+	h := NewHub(b)
+	room := newRoom("room", "classic", b, h)
+	alice := room.Reserve("alice", common.TeamRed)
+	bob := room.Reserve("bob", common.TeamBlue)
+	now := time.Now()
+
+	advance := func(at time.Time) Phase {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		h.advanceLocked(at)
+		return h.phase
+	}
+
+	connect(h, alice)
+	if phase := advance(now); phase != PhaseWaiting {
+		t.Fatalf("expected to wait for bob, got %s", phase)
+	}
+	connect(h, bob)
+	if phase := advance(now); phase != PhaseReadyCheck {
+		t.Fatalf("expected a ready check once both are here, got %s", phase)
+	}
+	h.setReady("alice")
+	if phase := h.Phase(); phase != PhaseReadyCheck {
+		t.Fatalf("expected to wait for bob to be ready, got %s", phase)
+	}
+	h.setReady("bob")
+	if phase := h.Phase(); phase != PhaseCountdown {
+		t.Fatalf("expected the countdown once both are ready, got %s", phase)
+	}
+	if phase := advance(time.Now().Add(DefaultLobby.Countdown)); phase != PhaseRunning {
+		t.Fatalf("expected the battle to start after the countdown, got %s", phase)
+	}
+	if !b.Enabled || b.TickCount != 0 {
+		t.Error("expected the battle not to have moved before it started")
+	}
+}
+
+func TestLobbyTimeout(t *testing.T) {
+	b := battle.NewBattle()
+	h := NewHub(b)
+	room := newRoom("room", "classic", b, h)
+	connect(h, room.Reserve("alice", common.TeamRed))
+	room.Reserve("bob", common.TeamBlue)
+
+	h.mu.Lock()
+	h.advanceLocked(time.Now().Add(DefaultLobby.JoinTimeout + time.Second))
+	h.mu.Unlock()
+	if h.Phase() != PhaseFinished || b.Enabled {
+		t.Errorf("expected the room to be cancelled when bob never shows up, got %s", h.Phase())
+	}
+}
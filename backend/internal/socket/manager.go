@@ -1,22 +1,37 @@
 package socket
 
 import (
+	"log"
 	"sync"
+	"time"
 
 	"cse-110-project-team-30/backend/internal/battle"
+	"cse-110-project-team-30/backend/internal/battle/maps"
 
 	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
 )
 
 type PlayerConn struct {
-	Conn     *websocket.Conn
-	UserID   string
-	Username string
+	Conn       *websocket.Conn
+	UserID     string
+	Username   string
+	Map        string    // map the player asked to play on, empty for any
+	Mode       string    // matchmaking mode, e.g. "1v1" or "2v2"
+	QueuedAt   time.Time // when the player joined the matchmaking queue
+	Difficulty string    // bot level the player wants for empty seats
 }
+
+// DefaultIdleTimeout is how long a room may go without any player connected
+// before the reaper closes it. It outlasts the reconnect grace so a dropped
+// player can always come back.
+const DefaultIdleTimeout = 3 * time.Minute
+
 type BattleManager struct {
 	mu    sync.Mutex
 	rooms map[string]*Room
+
+	tick, send time.Duration // rates for new rooms, the hub's defaults if zero
 }
 
 func NewBattleManager() *BattleManager {
@@ -39,31 +54,94 @@ func (m *BattleManager) DeleteRoom(roomID string) {
 
 	// Remove the room from manager
 	delete(m.rooms, roomID)
+	log.Printf("room %s closed: %s", roomID, room.Hub.EndReason())
 }
 
+// StartReaper closes rooms that have had no player connected for
+// idleTimeout, checking a few times per timeout. It runs for the life of
+// the manager.
+func (m *BattleManager) StartReaper(idleTimeout time.Duration) {
+	go func() {
+		ticker := time.NewTicker(max(idleTimeout/4, time.Second))
+		defer ticker.Stop()
+		for now := range ticker.C {
+			m.reap(now, idleTimeout)
+		}
+	}()
+}
+
+// reap closes every room that has been empty for idleTimeout: abandoned if
+// players had joined and left, empty if nobody ever came.
+func (m *BattleManager) reap(now time.Time, idleTimeout time.Duration) {
+	m.mu.Lock()
+	reap := make(map[*Room]EndReason)
+	for _, room := range m.rooms {
+		if d, joined := room.Hub.Idle(now); d >= idleTimeout {
+			reap[room] = EndEmpty
+			if joined {
+				reap[room] = EndAbandoned
+			}
+		}
+	}
+	m.mu.Unlock()
+
+	for room, reason := range reap {
+		room.Hub.end(reason)
+		m.DeleteRoom(room.ID)
+	}
+}
+
+// SetRates makes every room created from now on tick every tick and send
+// updates every send; see Hub.SetRates.
+func (m *BattleManager) SetRates(tick, send time.Duration) error {
+	if err := CheckRates(tick, send); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.tick, m.send = tick, send
+	return nil
+}
+
+// CreateRoom creates a room on the default map.
 func (m *BattleManager) CreateRoom() *Room {
+	room, err := m.CreateRoomWithMap(maps.DefaultName)
+	if err != nil {
+		panic(err) // the default map is built in and always loads
+	}
+	return room
+}
+
+// CreateRoomWithMap creates a room playing on the named map.
+func (m *BattleManager) CreateRoomWithMap(mapName string) (*Room, error) {
+	def, err := maps.Get(mapName)
+	if err != nil {
+		return nil, err
+	}
+
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
 	id := uuid.NewString()
 
-	b := battle.NewBattle()
+	b := battle.NewBattleFromMap(def)
 
 	b.OnDelete = func() {
 		m.DeleteRoom(id)
 	}
 	h := NewHub(b)
 
-	room := &Room{
-		ID:     id,
-		Battle: b,
-		Hub:    h,
-	}
+	room := newRoom(id, def.Name, b, h)
 
 	m.rooms[id] = room
 	go h.Run()
+	if m.tick > 0 {
+		if err := h.SetRates(m.tick, m.send); err != nil {
+			return nil, err
+		}
+	}
 
-	return room
+	return room, nil
 }
 
 func (m *BattleManager) GetRoom(id string) (*Room, bool) {
@@ -0,0 +1,248 @@
+package socket
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"cse-110-project-team-30/backend/internal/battle"
+	"cse-110-project-team-30/backend/internal/battle/common"
+)
+
+// Every message on a room socket after the auth message is an envelope.
+// Clients pick the ID of their requests; replies echo it so the client can
+// match an ack or error to what it sent.
+type envelope struct {
+	Type    string          `json:"type"`
+	ID      string          `json:"id,omitempty"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// outbound is an envelope the server sends.
+type outbound struct {
+	Type    string `json:"type"`
+	ID      string `json:"id,omitempty"`
+	Payload any    `json:"payload,omitempty"`
+}
+
+// Server message types.
+const (
+	msgState   = "state"   // payload is a keyframe or delta
+	msgJoined  = "joined"  // sent once on connecting; payload is a joinedPayload
+	msgAck     = "ack"     // the request with this ID succeeded
+	msgError   = "error"   // the request with this ID failed; payload is an errorPayload
+	msgLatency = "latency" // sent after each heartbeat; payload is a latencyPayload
+)
+
+// Error codes sent back to clients.
+const (
+	codeBadRequest  = "badRequest"  // the message or its payload could not be decoded
+	codeUnknownType = "unknownType" // no handler for the message type
+	codeRejected    = "rejected"    // the battle refused the request for another reason
+	codeSpectator   = "spectator"   // spectators can't send commands
+	codeNotStarted  = "notStarted"  // the battle hasn't started yet
+	codeBusy        = "busy"        // the room has too many commands waiting; try again
+	codeRateLimited = "rateLimited" // the client sent too many messages; some were dropped
+)
+
+// joinedPayload tells a client where they sit and, for players, what is in
+// their hand and how many resources they have.
+type joinedPayload struct {
+	Seat      *Seat          `json:"seat"`
+	Resumed   bool           `json:"resumed"`
+	Spectator bool           `json:"spectator,omitempty"`
+	Player    *battle.Player `json:"player,omitempty"`
+}
+
+// latencyPayload tells a client how long its last heartbeat took to come
+// back.
+type latencyPayload struct {
+	RTT int64 `json:"rtt"` // round trip time in milliseconds
+}
+
+type errorPayload struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// requestError is an error with the code the client gets.
+type requestError struct {
+	code string
+	err  error
+}
+
+func (e *requestError) Error() string { return e.err.Error() }
+func (e *requestError) Unwrap() error { return e.err }
+
+func badRequest(err error) error {
+	return &requestError{code: codeBadRequest, err: err}
+}
+
+// battleErrorCodes turns the battle's rejection reasons into stable codes
+// the client can translate for the player.
+var battleErrorCodes = []struct {
+	err  error
+	code string
+}{
+	{battle.ErrBattleOver, "battleOver"},
+	{battle.ErrTeamEliminated, "teamEliminated"},
+	{battle.ErrOutOfBounds, "outOfBounds"},
+	{battle.ErrBlockedTerrain, "blockedTerrain"},
+	{battle.ErrEnemyTerritory, "enemyTerritory"},
+	{battle.ErrUnknownTroop, "unknownTroop"},
+	{battle.ErrNotInBattle, "notInBattle"},
+	{battle.ErrNotInHand, "notInHand"},
+	{battle.ErrNotEnoughResources, "notEnoughResources"},
+	{battle.ErrPaused, "paused"},
+	{battle.ErrNotPaused, "notPaused"},
+	{battle.ErrNoPauseBudget, "noPauseBudget"},
+}
+
+func errorCode(err error) string {
+	var reqErr *requestError
+	if errors.As(err, &reqErr) {
+		return reqErr.code
+	}
+	for _, c := range battleErrorCodes {
+		if errors.Is(err, c.err) {
+			return c.code
+		}
+	}
+	return codeRejected
+}
+
+// handlerFunc handles one type of client message on the room loop. What it
+// returns is sent back as the payload of the ack; an error is sent back
+// instead.
+type handlerFunc func(h *Hub, cmd command) (any, error)
+
+// handlers maps client message types to their handlers.
+var handlers = map[string]handlerFunc{
+	"spawn":   handleSpawn,
+	"resync":  handleResync,
+	"pause":   handlePause,
+	"resume":  handleResume,
+	"forfeit": handleForfeit,
+	"ready":   handleReady,
+}
+
+// spectatorTypes are the messages spectators may send; they only affect
+// what the spectator is sent.
+var spectatorTypes = map[string]bool{
+	"resync": true,
+}
+
+// inBattleTypes are the messages that only make sense once the battle is
+// running.
+var inBattleTypes = map[string]bool{
+	"spawn":  true,
+	"pause":  true,
+	"resume": true,
+}
+
+// spawnRequest places a troop for the sender's team. Older clients also
+// send a team, which is ignored: the team comes from the sender's seat.
+type spawnRequest struct {
+	TroopType string `json:"troopType"`
+	X         int    `json:"x"`
+	Y         int    `json:"y"`
+}
+
+type spawnResult struct {
+	EntityID int `json:"entityID"`
+}
+
+func handleSpawn(h *Hub, cmd command) (any, error) {
+	var req spawnRequest
+	if err := json.Unmarshal(cmd.Payload, &req); err != nil {
+		return nil, badRequest(fmt.Errorf("invalid spawn payload: %w", err))
+	}
+	// Players are added with their seat's team when they connect
+	troop, err := h.battle.SpawnTroopAs(cmd.cl.UserID, common.NewPosition(req.X, req.Y), req.TroopType)
+	if err != nil {
+		return nil, err
+	}
+	return spawnResult{EntityID: troop.ID}, nil
+}
+
+// Pauses are timed from when the player asked, not from when the room got
+// round to it.
+func handlePause(h *Hub, cmd command) (any, error) {
+	return nil, h.battle.Pause(cmd.cl.UserID, cmd.at)
+}
+
+func handleResume(h *Hub, cmd command) (any, error) {
+	return nil, h.battle.Resume(cmd.cl.UserID, cmd.at)
+}
+
+func handleForfeit(h *Hub, cmd command) (any, error) {
+	return nil, h.battle.Forfeit(cmd.cl.UserID)
+}
+
+func handleReady(h *Hub, cmd command) (any, error) {
+	if cmd.cl.Seat == nil {
+		return nil, battle.ErrNotInBattle
+	}
+	h.setReady(cmd.cl.UserID)
+	return nil, nil
+}
+
+func handleResync(h *Hub, cmd command) (any, error) {
+	h.requestResync(cmd.cl)
+	return nil, nil
+}
+
+// check reports why a client may never send a message type, if it may not.
+// It doesn't depend on the battle, so readers check messages before
+// queueing them.
+func check(cl *client, msgType string) error {
+	if _, ok := handlers[msgType]; !ok {
+		return &requestError{code: codeUnknownType, err: fmt.Errorf("unknown message type %q", msgType)}
+	}
+	if cl.Spectator && !spectatorTypes[msgType] {
+		return &requestError{code: codeSpectator, err: fmt.Errorf("spectators can't send %q", msgType)}
+	}
+	return nil
+}
+
+// dispatch runs the handler for a command and builds the reply. Only the
+// room loop calls it, so handlers can change the battle without a lock.
+func (h *Hub) dispatch(cmd command) outbound {
+	if err := check(cmd.cl, cmd.Type); err != nil {
+		return errorReply(cmd.ID, err)
+	}
+	// The phase is checked here rather than when the command was queued,
+	// so a spawn sent right after "ready" sees the battle start
+	if inBattleTypes[cmd.Type] && h.beforeStart() {
+		err := &requestError{code: codeNotStarted, err: fmt.Errorf("the battle hasn't started yet")}
+		return errorReply(cmd.ID, err)
+	}
+	result, err := handlers[cmd.Type](h, cmd)
+	if err != nil {
+		return errorReply(cmd.ID, err)
+	}
+	return outbound{Type: msgAck, ID: cmd.ID, Payload: result}
+}
+
+func errorReply(id string, err error) outbound {
+	return outbound{Type: msgError, ID: id, Payload: errorPayload{Code: errorCode(err), Message: err.Error()}}
+}
+
+// decodeEnvelope reads a client message. Messages from before the envelope
+// existed are bare spawn requests, so a message without a type is treated
+// as one.
+func decodeEnvelope(frameType int, data []byte) (envelope, error) {
+	var env envelope
+	if err := decodeFrame(frameType, data, &env); err != nil {
+		return envelope{}, badRequest(fmt.Errorf("invalid message: %w", err))
+	}
+	if env.Type == "" && env.Payload == nil {
+		var req spawnRequest
+		if err := decodeFrame(frameType, data, &req); err != nil {
+			return envelope{}, badRequest(fmt.Errorf("invalid message: %w", err))
+		}
+		env.Type = "spawn"
+		env.Payload, _ = json.Marshal(req)
+	}
+	return env, nil
+}
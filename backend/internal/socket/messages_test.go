@@ -0,0 +1,109 @@
+package socket
+
+import (
+	"testing"
+
+	"cse-110-project-team-30/backend/internal/battle"
+	"cse-110-project-team-30/backend/internal/battle/common"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestDecodeEnvelope(t *testing.T) {
+	env, err := decodeEnvelope(websocket.TextMessage, []byte(`{"type":"resync","id":"7"}`))
+	if err != nil || env.Type != "resync" || env.ID != "7" {
+		t.Errorf("expected a resync envelope, got %+v, %v", env, err)
+	}
+
+	// Bare spawn messages from older clients still work
+	env, err = decodeEnvelope(websocket.TextMessage, []byte(`{"troopType":"ArcherOne","team":"blue","x":3,"y":4}`))
+	if err != nil || env.Type != "spawn" {
+		t.Fatalf("expected a legacy spawn, got %+v, %v", env, err)
+	}
+
+	data, err := marshalMsgpack(envelope{Type: "resync", ID: "8"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if env, err = decodeEnvelope(websocket.BinaryMessage, data); err != nil || env.ID != "8" {
+		t.Errorf("expected a msgpack envelope, got %+v, %v", env, err)
+	}
+
+	if _, err := decodeEnvelope(websocket.TextMessage, []byte(`{"type":`)); errorCode(err) != codeBadRequest {
+		t.Errorf("expected badRequest for broken JSON, got %v", err)
+	}
+}
+
+func TestDispatchReplies(t *testing.T) {
+	b := battle.NewBattle()
+	h := NewHub(b)
+	h.phase = PhaseRunning // skip the lobby
+	cl := &client{ClientInfo: ClientInfo{UserID: "alice"}}
+
+	reply := h.dispatch(newCommand(cl, envelope{Type: "spawn", ID: "0", Payload: []byte(`{"troopType":"SwordsmanOne","x":2,"y":2}`)}))
+	if reply.Type != msgError || reply.Payload.(errorPayload).Code != "notInBattle" {
+		t.Errorf("expected a player without a seat to be turned away, got %+v", reply)
+	}
+
+	b.AddPlayer("alice", common.TeamRed, []string{"SwordsmanOne"})
+	reply = h.dispatch(newCommand(cl, envelope{Type: "spawn", ID: "1", Payload: []byte(`{"troopType":"ArcherFour","x":2,"y":2}`)}))
+	if reply.Type != msgError || reply.ID != "1" || reply.Payload.(errorPayload).Code != "notInHand" {
+		t.Errorf("expected a notInHand error for request 1, got %+v", reply)
+	}
+
+	// The team in the payload is ignored: alice is red
+	reply = h.dispatch(newCommand(cl, envelope{Type: "spawn", ID: "2", Payload: []byte(`{"troopType":"SwordsmanOne","team":"blue","x":2,"y":2}`)}))
+	if reply.Type != msgAck || reply.ID != "2" || reply.Payload.(spawnResult).EntityID == 0 {
+		t.Fatalf("expected an ack for request 2, got %+v", reply)
+	}
+	for _, e := range b.Troops {
+		if e.GetTroop().ID == reply.Payload.(spawnResult).EntityID && e.GetTeam() != common.TeamRed {
+			t.Errorf("expected alice's troop to be red, got team %d", e.GetTeam())
+		}
+	}
+}
+
+func TestSpectatorsCantSendCommands(t *testing.T) {
+	h := NewHub(battle.NewBattle())
+	cl := &client{ClientInfo: ClientInfo{UserID: "carol", Spectator: true}}
+
+	reply := h.dispatch(newCommand(cl, envelope{Type: "spawn", ID: "1", Payload: []byte(`{"troopType":"SwordsmanOne","x":2,"y":2}`)}))
+	if reply.Type != msgError || reply.Payload.(errorPayload).Code != codeSpectator {
+		t.Errorf("expected a spectator error, got %+v", reply)
+	}
+	if reply = h.dispatch(newCommand(cl, envelope{Type: "resync", ID: "2"})); reply.Type != msgAck {
+		t.Errorf("expected spectators to be able to resync, got %+v", reply)
+	}
+}
+
+func TestPauseAndForfeitMessages(t *testing.T) {
+	b := battle.NewBattle()
+	h := NewHub(b)
+	h.phase = PhaseRunning // skip the lobby
+	alice := &client{ClientInfo: ClientInfo{UserID: "alice"}}
+	bob := &client{ClientInfo: ClientInfo{UserID: "bob"}}
+	b.AddPlayer("alice", common.TeamRed, nil)
+	b.AddPlayer("bob", common.TeamBlue, nil)
+
+	if reply := h.dispatch(newCommand(alice, envelope{Type: "pause", ID: "1"})); reply.Type != msgAck || b.Paused == nil {
+		t.Fatalf("expected alice to pause the battle, got %+v", reply)
+	}
+	if reply := h.dispatch(newCommand(bob, envelope{Type: "pause", ID: "2"})); reply.Type != msgError || reply.Payload.(errorPayload).Code != "paused" {
+		t.Errorf("expected a paused error, got %+v", reply)
+	}
+	if state := h.snapshot(nil); state.Paused == nil || state.Paused.Team != common.TeamRed {
+		t.Errorf("expected the state to show red's pause, got %+v", state.Paused)
+	}
+	h.dispatch(newCommand(alice, envelope{Type: "resume", ID: "3"}))
+	h.dispatch(newCommand(bob, envelope{Type: "resume", ID: "4"}))
+	if b.Paused != nil {
+		t.Fatal("expected the battle to resume once both sides asked")
+	}
+
+	if reply := h.dispatch(newCommand(bob, envelope{Type: "forfeit", ID: "5"})); reply.Type != msgAck {
+		t.Fatalf("expected bob's forfeit to be accepted, got %+v", reply)
+	}
+	if b.Winner == nil || *b.Winner != common.TeamRed {
+		t.Errorf("expected red to win after bob forfeits, got %v", b.Winner)
+	}
+}
@@ -0,0 +1,612 @@
+package socket
+
+import (
+	"encoding"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// A small MessagePack encoder and decoder. Messages are defined once as Go
+// structs with json tags; the encoder walks them the way encoding/json does
+// (same field names, omitempty, embedded structs, map keys as strings), so a
+// MessagePack message decodes to the same object as its JSON twin. Whole
+// numbers are sent as integers, which is where most of the savings on
+// positions and health come from.
+
+var (
+	jsonMarshalerType = reflect.TypeFor[json.Marshaler]()
+	textMarshalerType = reflect.TypeFor[encoding.TextMarshaler]()
+)
+
+func marshalMsgpack(v any) ([]byte, error) {
+	e := &msgpackEncoder{buf: make([]byte, 0, 512)}
+	if err := e.encode(reflect.ValueOf(v)); err != nil {
+		return nil, err
+	}
+	return e.buf, nil
+}
+
+// unmarshalMsgpack decodes a MessagePack message into v using v's json tags.
+// Client messages are tiny, so it simply goes through encoding/json.
+func unmarshalMsgpack(data []byte, v any) error {
+	d := &msgpackDecoder{buf: data}
+	generic, err := d.decode()
+	if err != nil {
+		return err
+	}
+	if d.pos != len(d.buf) {
+		return errors.New("msgpack: trailing data")
+	}
+	raw, err := json.Marshal(generic)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, v)
+}
+
+// --------------------
+// Encoder
+// --------------------
+
+type msgpackEncoder struct {
+	buf []byte
+}
+
+func (e *msgpackEncoder) encode(v reflect.Value) error {
+	if !v.IsValid() {
+		e.nil()
+		return nil
+	}
+	if v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			e.nil()
+			return nil
+		}
+		if v.Type().Implements(jsonMarshalerType) || v.Type().Implements(textMarshalerType) {
+			return e.encodeMarshaler(v)
+		}
+		return e.encode(v.Elem())
+	}
+	if v.Type().Implements(jsonMarshalerType) || v.Type().Implements(textMarshalerType) {
+		return e.encodeMarshaler(v)
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		if v.Bool() {
+			e.buf = append(e.buf, 0xc3)
+		} else {
+			e.buf = append(e.buf, 0xc2)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		e.int(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		e.uint(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		e.float(v.Float(), v.Kind() == reflect.Float32)
+	case reflect.String:
+		e.string(v.String())
+	case reflect.Slice:
+		if v.IsNil() {
+			e.nil()
+			return nil
+		}
+		return e.encodeArray(v)
+	case reflect.Array:
+		return e.encodeArray(v)
+	case reflect.Map:
+		if v.IsNil() {
+			e.nil()
+			return nil
+		}
+		return e.encodeMap(v)
+	case reflect.Struct:
+		return e.encodeStruct(v)
+	default:
+		return fmt.Errorf("msgpack: unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+// encodeMarshaler encodes a type with its own JSON or text form the same way
+// encoding/json would.
+func (e *msgpackEncoder) encodeMarshaler(v reflect.Value) error {
+	if m, ok := v.Interface().(json.Marshaler); ok {
+		raw, err := m.MarshalJSON()
+		if err != nil {
+			return err
+		}
+		var generic any
+		if err := json.Unmarshal(raw, &generic); err != nil {
+			return err
+		}
+		return e.encode(reflect.ValueOf(generic))
+	}
+	text, err := v.Interface().(encoding.TextMarshaler).MarshalText()
+	if err != nil {
+		return err
+	}
+	e.string(string(text))
+	return nil
+}
+
+func (e *msgpackEncoder) encodeArray(v reflect.Value) error {
+	e.header(v.Len(), 0x90, 0xdc, 0xdd)
+	for i := 0; i < v.Len(); i++ {
+		if err := e.encode(v.Index(i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// encodeMap writes map keys as strings in sorted order, like encoding/json.
+func (e *msgpackEncoder) encodeMap(v reflect.Value) error {
+	type entry struct {
+		key   string
+		value reflect.Value
+	}
+	entries := make([]entry, 0, v.Len())
+	iter := v.MapRange()
+	for iter.Next() {
+		key, err := mapKey(iter.Key())
+		if err != nil {
+			return err
+		}
+		entries = append(entries, entry{key, iter.Value()})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].key < entries[j].key })
+
+	e.header(len(entries), 0x80, 0xde, 0xdf)
+	for _, en := range entries {
+		e.string(en.key)
+		if err := e.encode(en.value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func mapKey(k reflect.Value) (string, error) {
+	if k.Kind() == reflect.String {
+		return k.String(), nil
+	}
+	if m, ok := k.Interface().(encoding.TextMarshaler); ok {
+		text, err := m.MarshalText()
+		return string(text), err
+	}
+	switch k.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(k.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(k.Uint(), 10), nil
+	}
+	return "", fmt.Errorf("msgpack: unsupported map key type %s", k.Type())
+}
+
+func (e *msgpackEncoder) encodeStruct(v reflect.Value) error {
+	fields := cachedFields(v.Type())
+	values := make([]reflect.Value, len(fields))
+	n := 0
+	for i, f := range fields {
+		fv, ok := fieldByIndex(v, f.index)
+		if !ok || (f.omitEmpty && isEmptyValue(fv)) {
+			continue
+		}
+		values[i] = fv
+		n++
+	}
+
+	e.header(n, 0x80, 0xde, 0xdf)
+	for i, f := range fields {
+		if !values[i].IsValid() {
+			continue
+		}
+		e.string(f.name)
+		if err := e.encode(values[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fieldByIndex is v.FieldByIndex that reports false instead of panicking
+// when it runs into a nil embedded pointer.
+func fieldByIndex(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
+
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64, reflect.Interface, reflect.Pointer:
+		return v.IsZero()
+	}
+	return false
+}
+
+func (e *msgpackEncoder) nil() {
+	e.buf = append(e.buf, 0xc0)
+}
+
+func (e *msgpackEncoder) int(i int64) {
+	switch {
+	case i >= 0:
+		e.uint(uint64(i))
+	case i >= -32:
+		e.buf = append(e.buf, byte(i))
+	case i >= math.MinInt8:
+		e.buf = append(e.buf, 0xd0, byte(i))
+	case i >= math.MinInt16:
+		e.buf = binary.BigEndian.AppendUint16(append(e.buf, 0xd1), uint16(i))
+	case i >= math.MinInt32:
+		e.buf = binary.BigEndian.AppendUint32(append(e.buf, 0xd2), uint32(i))
+	default:
+		e.buf = binary.BigEndian.AppendUint64(append(e.buf, 0xd3), uint64(i))
+	}
+}
+
+func (e *msgpackEncoder) uint(u uint64) {
+	switch {
+	case u <= 0x7f:
+		e.buf = append(e.buf, byte(u))
+	case u <= math.MaxUint8:
+		e.buf = append(e.buf, 0xcc, byte(u))
+	case u <= math.MaxUint16:
+		e.buf = binary.BigEndian.AppendUint16(append(e.buf, 0xcd), uint16(u))
+	case u <= math.MaxUint32:
+		e.buf = binary.BigEndian.AppendUint32(append(e.buf, 0xce), uint32(u))
+	default:
+		e.buf = binary.BigEndian.AppendUint64(append(e.buf, 0xcf), u)
+	}
+}
+
+// float sends whole numbers as integers and everything else at the
+// precision of the Go type.
+func (e *msgpackEncoder) float(f float64, is32 bool) {
+	if f == math.Trunc(f) && math.Abs(f) < 1<<53 {
+		e.int(int64(f))
+		return
+	}
+	if is32 {
+		e.buf = binary.BigEndian.AppendUint32(append(e.buf, 0xca), math.Float32bits(float32(f)))
+		return
+	}
+	e.buf = binary.BigEndian.AppendUint64(append(e.buf, 0xcb), math.Float64bits(f))
+}
+
+func (e *msgpackEncoder) string(s string) {
+	n := len(s)
+	switch {
+	case n < 32:
+		e.buf = append(e.buf, 0xa0|byte(n))
+	case n <= math.MaxUint8:
+		e.buf = append(e.buf, 0xd9, byte(n))
+	case n <= math.MaxUint16:
+		e.buf = binary.BigEndian.AppendUint16(append(e.buf, 0xda), uint16(n))
+	default:
+		e.buf = binary.BigEndian.AppendUint32(append(e.buf, 0xdb), uint32(n))
+	}
+	e.buf = append(e.buf, s...)
+}
+
+// header writes an array or map length using the fix, 16-bit or 32-bit form.
+func (e *msgpackEncoder) header(n int, fix, b16, b32 byte) {
+	switch {
+	case n < 16:
+		e.buf = append(e.buf, fix|byte(n))
+	case n <= math.MaxUint16:
+		e.buf = binary.BigEndian.AppendUint16(append(e.buf, b16), uint16(n))
+	default:
+		e.buf = binary.BigEndian.AppendUint32(append(e.buf, b32), uint32(n))
+	}
+}
+
+// --------------------
+// Struct fields
+// --------------------
+
+type structField struct {
+	name      string
+	index     []int
+	omitEmpty bool
+}
+
+var fieldCache sync.Map // reflect.Type -> []structField
+
+func cachedFields(t reflect.Type) []structField {
+	if f, ok := fieldCache.Load(t); ok {
+		return f.([]structField)
+	}
+	f, _ := fieldCache.LoadOrStore(t, typeFields(t))
+	return f.([]structField)
+}
+
+// typeFields lists the fields encoding/json would write for t, in the same
+// order. Fields of embedded structs are promoted unless a shallower field
+// has the same name; same-depth conflicts drop the field, as in JSON.
+func typeFields(t reflect.Type) []structField {
+	type candidate struct {
+		structField
+		depth  int
+		tagged bool
+	}
+	var all []candidate
+
+	var walk func(t reflect.Type, index []int, depth int)
+	walk = func(t reflect.Type, index []int, depth int) {
+		for i := 0; i < t.NumField(); i++ {
+			sf := t.Field(i)
+			tag := sf.Tag.Get("json")
+			if tag == "-" {
+				continue
+			}
+			name, opts, _ := strings.Cut(tag, ",")
+			idx := append(append([]int{}, index...), i)
+
+			if sf.Anonymous && name == "" {
+				ft := sf.Type
+				if ft.Kind() == reflect.Pointer {
+					ft = ft.Elem()
+				}
+				if ft.Kind() == reflect.Struct {
+					walk(ft, idx, depth+1)
+					continue
+				}
+			}
+			if !sf.IsExported() {
+				continue
+			}
+			tagged := name != ""
+			if !tagged {
+				name = sf.Name
+			}
+			all = append(all, candidate{
+				structField: structField{name: name, index: idx, omitEmpty: strings.Contains(opts, "omitempty")},
+				depth:       depth,
+				tagged:      tagged,
+			})
+		}
+	}
+	walk(t, nil, 0)
+
+	// For each name keep the shallowest field; at equal depth a lone tagged
+	// field wins and anything else is ambiguous and dropped.
+	type rank struct{ depth, count, tagged int }
+	ranks := map[string]*rank{}
+	for _, c := range all {
+		r := ranks[c.name]
+		if r == nil || c.depth < r.depth {
+			r = &rank{depth: c.depth}
+			ranks[c.name] = r
+		}
+		if c.depth == r.depth {
+			r.count++
+			if c.tagged {
+				r.tagged++
+			}
+		}
+	}
+	winner := map[string]int{}
+	for i, c := range all {
+		r := ranks[c.name]
+		if c.depth == r.depth && (r.count == 1 || (r.tagged == 1 && c.tagged)) {
+			winner[c.name] = i
+		}
+	}
+	var fields []structField
+	for i, c := range all {
+		if j, ok := winner[c.name]; ok && j == i {
+			fields = append(fields, c.structField)
+		}
+	}
+	return fields
+}
+
+// --------------------
+// Decoder
+// --------------------
+
+type msgpackDecoder struct {
+	buf []byte
+	pos int
+}
+
+var errShortMsgpack = errors.New("msgpack: unexpected end of data")
+
+func (d *msgpackDecoder) next(n int) ([]byte, error) {
+	if n < 0 || d.pos+n > len(d.buf) {
+		return nil, errShortMsgpack
+	}
+	b := d.buf[d.pos : d.pos+n]
+	d.pos += n
+	return b, nil
+}
+
+func (d *msgpackDecoder) length(size int) (int, error) {
+	b, err := d.next(size)
+	if err != nil {
+		return 0, err
+	}
+	switch size {
+	case 1:
+		return int(b[0]), nil
+	case 2:
+		return int(binary.BigEndian.Uint16(b)), nil
+	default:
+		return int(binary.BigEndian.Uint32(b)), nil
+	}
+}
+
+// decode reads one value into the types encoding/json would produce:
+// map[string]any, []any, float64, string, bool or nil.
+func (d *msgpackDecoder) decode() (any, error) {
+	b, err := d.next(1)
+	if err != nil {
+		return nil, err
+	}
+	c := b[0]
+	switch {
+	case c <= 0x7f:
+		return float64(c), nil
+	case c >= 0xe0:
+		return float64(int8(c)), nil
+	case c&0xf0 == 0x80:
+		return d.decodeMap(int(c & 0x0f))
+	case c&0xf0 == 0x90:
+		return d.decodeArray(int(c & 0x0f))
+	case c&0xe0 == 0xa0:
+		return d.decodeString(int(c & 0x1f))
+	}
+
+	switch c {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		b, err := d.next(1 << (c - 0xcc))
+		if err != nil {
+			return nil, err
+		}
+		return float64(readUint(b)), nil
+	case 0xd0, 0xd1, 0xd2, 0xd3:
+		b, err := d.next(1 << (c - 0xd0))
+		if err != nil {
+			return nil, err
+		}
+		return float64(readInt(b)), nil
+	case 0xca:
+		b, err := d.next(4)
+		if err != nil {
+			return nil, err
+		}
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(b))), nil
+	case 0xcb:
+		b, err := d.next(8)
+		if err != nil {
+			return nil, err
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(b)), nil
+	case 0xd9, 0xda, 0xdb, 0xc4, 0xc5, 0xc6:
+		size := 1
+		switch c {
+		case 0xda, 0xc5:
+			size = 2
+		case 0xdb, 0xc6:
+			size = 4
+		}
+		n, err := d.length(size)
+		if err != nil {
+			return nil, err
+		}
+		return d.decodeString(n)
+	case 0xdc, 0xdd:
+		n, err := d.length(2 << (c - 0xdc))
+		if err != nil {
+			return nil, err
+		}
+		return d.decodeArray(n)
+	case 0xde, 0xdf:
+		n, err := d.length(2 << (c - 0xde))
+		if err != nil {
+			return nil, err
+		}
+		return d.decodeMap(n)
+	}
+	return nil, fmt.Errorf("msgpack: unsupported type byte 0x%02x", c)
+}
+
+func (d *msgpackDecoder) decodeString(n int) (any, error) {
+	b, err := d.next(n)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+func (d *msgpackDecoder) decodeArray(n int) (any, error) {
+	if n > len(d.buf)-d.pos {
+		return nil, errShortMsgpack
+	}
+	out := make([]any, n)
+	for i := range out {
+		v, err := d.decode()
+		if err != nil {
+			return nil, err
+		}
+		out[i] = v
+	}
+	return out, nil
+}
+
+func (d *msgpackDecoder) decodeMap(n int) (any, error) {
+	if n > len(d.buf)-d.pos {
+		return nil, errShortMsgpack
+	}
+	out := make(map[string]any, n)
+	for i := 0; i < n; i++ {
+		k, err := d.decode()
+		if err != nil {
+			return nil, err
+		}
+		key, ok := k.(string)
+		if !ok {
+			return nil, errors.New("msgpack: map key is not a string")
+		}
+		v, err := d.decode()
+		if err != nil {
+			return nil, err
+		}
+		out[key] = v
+	}
+	return out, nil
+}
+
+func readUint(b []byte) uint64 {
+	switch len(b) {
+	case 1:
+		return uint64(b[0])
+	case 2:
+		return uint64(binary.BigEndian.Uint16(b))
+	case 4:
+		return uint64(binary.BigEndian.Uint32(b))
+	default:
+		return binary.BigEndian.Uint64(b)
+	}
+}
+
+func readInt(b []byte) int64 {
+	switch len(b) {
+	case 1:
+		return int64(int8(b[0]))
+	case 2:
+		return int64(int16(binary.BigEndian.Uint16(b)))
+	case 4:
+		return int64(int32(binary.BigEndian.Uint32(b)))
+	default:
+		return int64(binary.BigEndian.Uint64(b))
+	}
+}
@@ -0,0 +1,94 @@
+package socket
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"cse-110-project-team-30/backend/internal/battle"
+	"cse-110-project-team-30/backend/internal/battle/common"
+)
+
+func TestMsgpackMatchesJSON(t *testing.T) {
+	b := battle.NewBattle()
+	h := NewHub(b)
+	b.AddPlayer("alice", common.TeamRed, []string{"SwordsmanOne"})
+	troop, err := b.SpawnTroopAs("alice", common.NewPosition(3, 3), "SwordsmanOne")
+	if err != nil {
+		t.Fatal(err)
+	}
+	troop.Position.X = 3.5
+	b.Players["alice"].Resources = 4.3
+
+	messages := map[string]any{
+		"keyframe": keyframe{Kind: kindKeyframe, sharedState: h.snapshot(h.takeEvents()), Troops: b.Troops},
+		"delta":    delta{Kind: kindDelta, Moved: []entityMove{{ID: troop.ID, X: 3.5, Y: -40000}}, Removed: []int{-1}},
+	}
+	for name, msg := range messages {
+		text, err := EncodingJSON.Marshal(msg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		binary, err := EncodingMsgpack.Marshal(msg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(binary) >= len(text) {
+			t.Errorf("%s: expected msgpack to be smaller than JSON, got %d vs %d bytes", name, len(binary), len(text))
+		}
+
+		var fromJSON, fromMsgpack any
+		if err := json.Unmarshal(text, &fromJSON); err != nil {
+			t.Fatal(err)
+		}
+		if err := unmarshalMsgpack(binary, &fromMsgpack); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(fromJSON, fromMsgpack) {
+			t.Errorf("%s: msgpack and JSON decode differently:\njson:    %v\nmsgpack: %v", name, fromJSON, fromMsgpack)
+		}
+	}
+}
+
+func TestMsgpackClientMessage(t *testing.T) {
+	type spawn struct {
+		TroopType string `json:"troopType"`
+		X         int    `json:"x"`
+		Y         int    `json:"y"`
+	}
+	data, err := marshalMsgpack(spawn{TroopType: "ArcherOne", X: 300, Y: -2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got spawn
+	if err := unmarshalMsgpack(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got != (spawn{TroopType: "ArcherOne", X: 300, Y: -2}) {
+		t.Errorf("round trip changed the message: %+v", got)
+	}
+	if err := unmarshalMsgpack(data[:len(data)-1], &got); err == nil {
+		t.Error("expected truncated data to fail")
+	}
+}
+
+func TestTypeFieldsFollowJSON(t *testing.T) {
+	type inner struct {
+		Name  string `json:"name"`
+		Shown int
+	}
+	type outer struct {
+		inner
+		Name    string `json:"name"`
+		Skipped int    `json:"-"`
+		Empty   string `json:"empty,omitempty"`
+		hidden  int
+	}
+	var names []string
+	for _, f := range typeFields(reflect.TypeFor[outer]()) {
+		names = append(names, f.name)
+	}
+	if want := []string{"Shown", "name", "empty"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("expected fields %v, got %v", want, names)
+	}
+}
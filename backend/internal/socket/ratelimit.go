@@ -0,0 +1,138 @@
+package socket
+
+import (
+	"errors"
+	"expvar"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// MaxMessageSize is the largest message a client may send, auth message
+// included. A bigger one closes the connection.
+const MaxMessageSize = 4 << 10
+
+// Limits caps how fast clients may send messages. Every message over the
+// limit is dropped; a client that keeps going is warned, then disconnected.
+type Limits struct {
+	ConnRate, ConnBurst float64 // messages per second, and at once, per connection
+	UserRate, UserBurst float64 // the same per user, across their connections to a room
+
+	WarnAfter       int           // dropped messages before the client is warned
+	DisconnectAfter int           // dropped messages before the client is disconnected
+	Forgive         time.Duration // drops are forgotten after this long without one
+}
+
+// DefaultLimits leaves plenty of room for a player spamming spawns by hand.
+var DefaultLimits = Limits{
+	ConnRate:        10,
+	ConnBurst:       20,
+	UserRate:        15,
+	UserBurst:       30,
+	WarnAfter:       3,
+	DisconnectAfter: 50,
+	Forgive:         10 * time.Second,
+}
+
+// violations counts abuse by kind: "dropped", "warned", "disconnected" and
+// "tooLarge". It is left out of expvar's global list, so only
+// MetricsHandler serves it.
+var violations = new(expvar.Map)
+
+// MetricsHandler serves the violation counters as JSON, in the same shape as
+// expvar's /debug/vars. Serve it on an internal listener, not the public one.
+func MetricsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		fmt.Fprintf(w, "{\"socket_violations\": %s}\n", violations)
+	})
+}
+
+// tokenBucket lets through rate messages per second on average, and up to
+// burst at once.
+type tokenBucket struct {
+	rate, burst float64
+	tokens      float64
+	last        time.Time
+}
+
+func newTokenBucket(rate, burst float64, now time.Time) *tokenBucket {
+	return &tokenBucket{rate: rate, burst: burst, tokens: burst, last: now}
+}
+
+// refill adds the tokens earned since the bucket was last used.
+func (b *tokenBucket) refill(now time.Time) {
+	b.tokens = min(b.tokens+now.Sub(b.last).Seconds()*b.rate, b.burst)
+	b.last = now
+}
+
+// allow takes a token if there is one.
+func (b *tokenBucket) allow(now time.Time) bool {
+	b.refill(now)
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// allow reports whether the client may send another message now, going by
+// both its connection's and its user's limits. A message only costs a token
+// from either once both let it through. Only the client's reader calls it.
+func (h *Hub) allow(cl *client, now time.Time) bool {
+	if cl.bucket == nil {
+		cl.bucket = newTokenBucket(h.limits.ConnRate, h.limits.ConnBurst, now)
+	}
+	h.limitsMu.Lock()
+	defer h.limitsMu.Unlock()
+	user, ok := h.users[cl.UserID]
+	if !ok {
+		user = newTokenBucket(h.limits.UserRate, h.limits.UserBurst, now)
+		h.users[cl.UserID] = user
+	}
+	cl.bucket.refill(now)
+	user.refill(now)
+	if cl.bucket.tokens < 1 || user.tokens < 1 {
+		return false
+	}
+	cl.bucket.tokens--
+	user.tokens--
+	return true
+}
+
+// overLimit deals with a message dropped for going over the limits, and
+// reports whether the client should be disconnected. Only the client's
+// reader calls it.
+func (h *Hub) overLimit(cl *client, now time.Time) bool {
+	if now.Sub(cl.lastDrop) > h.limits.Forgive {
+		cl.drops = 0
+	}
+	cl.drops++
+	cl.lastDrop = now
+	violations.Add("dropped", 1)
+
+	switch {
+	case cl.drops >= h.limits.DisconnectAfter:
+		violations.Add("disconnected", 1)
+		log.Println("disconnecting", cl.UserID+": too many messages")
+		return true
+	case cl.drops == h.limits.WarnAfter:
+		violations.Add("warned", 1)
+		log.Println("rate limiting", cl.UserID)
+		err := &requestError{code: codeRateLimited, err: fmt.Errorf("too many messages; slow down or you will be disconnected")}
+		h.send(cl, errorReply("", err))
+	}
+	return false
+}
+
+// NoteReadError counts a connection closed for sending a message over
+// MaxMessageSize.
+func NoteReadError(userID string, err error) {
+	if errors.Is(err, websocket.ErrReadLimit) {
+		violations.Add("tooLarge", 1)
+		log.Println("closing connection of", userID+": message too large")
+	}
+}
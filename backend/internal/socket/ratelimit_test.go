@@ -0,0 +1,107 @@
+package socket
+
+import (
+	"encoding/json"
+	"expvar"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"cse-110-project-team-30/backend/internal/battle"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestTokenBucket(t *testing.T) {
+	now := time.Now()
+	b := newTokenBucket(2, 3, now)
+	for i := 0; i < 3; i++ {
+		if !b.allow(now) {
+			t.Fatalf("expected message %d of the burst to pass", i)
+		}
+	}
+	if b.allow(now) {
+		t.Fatal("expected the bucket to be empty after the burst")
+	}
+	if !b.allow(now.Add(500 * time.Millisecond)) {
+		t.Error("expected a token back after half a second at 2/s")
+	}
+	if b.allow(now.Add(500 * time.Millisecond)) {
+		t.Error("expected only one token back")
+	}
+	// A long rest only fills the bucket up to the burst
+	later := now.Add(time.Hour)
+	for i := 0; i < 3; i++ {
+		b.allow(later)
+	}
+	if b.allow(later) {
+		t.Error("expected the bucket to hold no more than the burst")
+	}
+}
+
+func TestRateLimitEscalates(t *testing.T) {
+	h := NewHub(battle.NewBattle())
+	h.limits = Limits{ConnRate: 1, ConnBurst: 2, UserRate: 1, UserBurst: 3, WarnAfter: 2, DisconnectAfter: 4, Forgive: time.Minute}
+	alice := newClient(&websocket.Conn{}, ClientInfo{UserID: "alice"})
+	h.clients[alice.conn] = alice
+	now := time.Now()
+	count := func(kind string) int64 {
+		if v, ok := violations.Get(kind).(*expvar.Int); ok {
+			return v.Value()
+		}
+		return 0
+	}
+	dropped := count("dropped")
+
+	// Each connection and each user has its own limit
+	if !h.allow(alice, now) || !h.allow(alice, now) || h.allow(alice, now) {
+		t.Fatal("expected alice's connection to send exactly its burst of 2")
+	}
+	second := newClient(&websocket.Conn{}, ClientInfo{UserID: "alice"})
+	if !h.allow(second, now) || h.allow(second, now) {
+		t.Fatal("expected alice's second connection to get only what is left of the user's burst")
+	}
+	if second.bucket.tokens != 1 {
+		t.Errorf("expected a message the user's limit turned away to cost the connection nothing, %v tokens left", second.bucket.tokens)
+	}
+
+	// Drop, then warn, then disconnect
+	if h.overLimit(alice, now) || len(alice.out) != 0 {
+		t.Fatal("expected the first drop to be silent")
+	}
+	if h.overLimit(alice, now) || len(alice.out) != 1 {
+		t.Fatal("expected a warning on the second drop")
+	}
+	var warning struct {
+		Payload errorPayload `json:"payload"`
+	}
+	json.Unmarshal((<-alice.out).data, &warning)
+	if warning.Payload.Code != codeRateLimited {
+		t.Errorf("expected a rateLimited warning, got %+v", warning.Payload)
+	}
+	h.overLimit(alice, now)
+	if !h.overLimit(alice, now) {
+		t.Error("expected the fourth drop to disconnect")
+	}
+	if got := count("dropped") - dropped; got != 4 {
+		t.Errorf("expected 4 drops counted, got %d", got)
+	}
+
+	// Drops are forgiven after a while
+	if h.overLimit(alice, now.Add(2*time.Minute)) || alice.drops != 1 {
+		t.Errorf("expected the count to start over after Forgive, got %d", alice.drops)
+	}
+}
+
+func TestMetricsHandler(t *testing.T) {
+	violations.Add("tooLarge", 1)
+	rec := httptest.NewRecorder()
+	MetricsHandler().ServeHTTP(rec, httptest.NewRequest("GET", "/debug/vars", nil))
+	var vars map[string]map[string]int64
+	if err := json.Unmarshal(rec.Body.Bytes(), &vars); err != nil {
+		t.Fatalf("expected JSON, got %q: %v", rec.Body.String(), err)
+	}
+	if len(vars) != 1 || vars["socket_violations"]["tooLarge"] < 1 {
+		t.Errorf("expected only the violation counters, got %v", vars)
+	}
+}
@@ -1,9 +1,180 @@
 package socket
 
-import "cse-110-project-team-30/backend/internal/battle"
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"cse-110-project-team-30/backend/internal/battle"
+	"cse-110-project-team-30/backend/internal/battle/common"
+
+	"github.com/gorilla/websocket"
+)
+
+// DefaultReconnectGrace is how long a room holds a dropped player's seat.
+const DefaultReconnectGrace = 2 * time.Minute
+
+// FogSpectatorDelay is how far behind spectators watch a match on a map with
+// fog of war, where they see more than the players do.
+const FogSpectatorDelay = 10 * time.Second
 
 type Room struct {
 	ID     string
+	Map    string
 	Hub    *Hub
 	Battle *battle.Battle
+
+	// ReconnectGrace is how long after losing their last connection a
+	// player can come back without a new ticket.
+	ReconnectGrace time.Duration
+
+	mu    sync.Mutex
+	seats map[string]*seatState // the roster, by user ID
+	next  int                   // index of the next seat given out
+}
+
+// Seat is a player's place in a room. The server decides it; whatever team
+// a client claims in its messages is ignored.
+type Seat struct {
+	UserID string      `json:"userID"`
+	Team   common.Team `json:"team"`
+	Index  int         `json:"seat"` // order the seat was given out in
+}
+
+// seatState tracks whether the seat's player is connected.
+type seatState struct {
+	Seat
+	joined bool      // the player has connected at least once
+	conns  int       // open connections
+	leftAt time.Time // when the last connection closed
+}
+
+func newRoom(id, mapName string, b *battle.Battle, h *Hub) *Room {
+	r := &Room{
+		ID:             id,
+		Map:            mapName,
+		Battle:         b,
+		Hub:            h,
+		ReconnectGrace: DefaultReconnectGrace,
+		seats:          make(map[string]*seatState),
+	}
+	h.OnDisconnect = r.disconnected
+	h.Roster = r.Seats
+	if b.HasFog() {
+		h.SetSpectatorDelay(FogSpectatorDelay)
+	}
+	return r
+}
+
+// Reserve seats a matched user on a team before they connect. Only users
+// with a seat can join the room.
+func (r *Room) Reserve(userID string, team common.Team) Seat {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if s, ok := r.seats[userID]; ok {
+		return s.Seat
+	}
+	s := &seatState{Seat: Seat{UserID: userID, Team: team, Index: r.next}}
+	r.next++
+	r.seats[userID] = s
+	return s.Seat
+}
+
+// Release takes back a reserved seat its user never joined, e.g. because
+// they could not be told about the match. It reports whether the seat was
+// released.
+func (r *Room) Release(userID string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s, ok := r.seats[userID]
+	if !ok || s.joined {
+		return false
+	}
+	delete(r.seats, userID)
+	return true
+}
+
+// Seat returns the user's seat, if they are on the room's roster.
+func (r *Room) Seat(userID string) (Seat, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s, ok := r.seats[userID]
+	if !ok {
+		return Seat{}, false
+	}
+	return s.Seat, true
+}
+
+// Seats lists the roster in seat order.
+func (r *Room) Seats() []Seat {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	seats := make([]Seat, 0, len(r.seats))
+	for _, s := range r.seats {
+		seats = append(seats, s.Seat)
+	}
+	sort.Slice(seats, func(i, j int) bool { return seats[i].Index < seats[j].Index })
+	return seats
+}
+
+// Resume gives a player who already joined their seat back without a
+// ticket, as long as they are still connected elsewhere or dropped less
+// than ReconnectGrace ago.
+func (r *Room) Resume(userID string) (Seat, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s, ok := r.seats[userID]
+	switch {
+	case !ok:
+		return Seat{}, errors.New("user is not on the room's roster")
+	case !s.joined:
+		return Seat{}, errors.New("a ticket is needed to join for the first time")
+	case s.conns == 0 && time.Since(s.leftAt) > r.ReconnectGrace:
+		return Seat{}, errors.New("the seat was given up after the reconnect window")
+	}
+	return s.Seat, nil
+}
+
+// Connected records a new connection for the user's seat and reports
+// whether they had joined before.
+func (r *Room) Connected(userID string) (returning bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s, ok := r.seats[userID]
+	if !ok {
+		return false
+	}
+	returning = s.joined
+	s.joined = true
+	s.conns++
+	return returning
+}
+
+// Join hands a seated player's connection to the room and records it for
+// their seat. If the room has stopped, the connection doesn't count.
+func (r *Room) Join(c *websocket.Conn, info ClientInfo) error {
+	info.Resumed = r.Connected(info.UserID)
+	if err := r.Hub.AddClient(c, info); err != nil {
+		r.disconnected(info.UserID)
+		return err
+	}
+	return nil
+}
+
+// disconnected records that one of the user's connections closed.
+func (r *Room) disconnected(userID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if s, ok := r.seats[userID]; ok && s.conns > 0 {
+		s.conns--
+		if s.conns == 0 {
+			s.leftAt = time.Now()
+		}
+	}
+}
+
+// Spectators counts the room's connected spectators.
+func (r *Room) Spectators() int {
+	return r.Hub.Spectators()
 }
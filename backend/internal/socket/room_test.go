@@ -0,0 +1,87 @@
+package socket
+
+import (
+	"testing"
+	"time"
+
+	"cse-110-project-team-30/backend/internal/battle/common"
+)
+
+func TestRoomSeats(t *testing.T) {
+	room := NewBattleManager().CreateRoom()
+	first := room.Reserve("alice", common.TeamRed)
+	second := room.Reserve("bob", common.TeamBlue)
+	again := room.Reserve("alice", common.TeamBlue)
+	if first.Index != 0 || second.Index != 1 || again != first {
+		t.Errorf("expected seats in reservation order, got %+v %+v %+v", first, second, again)
+	}
+
+	if seat, ok := room.Seat("bob"); !ok || seat.Team != common.TeamBlue {
+		t.Errorf("expected bob's reserved blue seat, got %+v", seat)
+	}
+	if seats := room.Seats(); len(seats) != 2 || seats[1].UserID != "bob" {
+		t.Errorf("expected the roster in seat order, got %+v", seats)
+	}
+	if _, ok := room.Seat("mallory"); ok {
+		t.Error("users off the roster should not have a seat")
+	}
+
+	room.Connected("bob")
+	if room.Release("bob") {
+		t.Error("a seat its player joined should not be released")
+	}
+	if !room.Release("alice") {
+		t.Fatal("expected alice's unused seat to be released")
+	}
+	carol := room.Reserve("carol", common.TeamRed)
+	if seats := room.Seats(); len(seats) != 2 || seats[0].UserID != "bob" || carol.Index != 2 {
+		t.Errorf("expected released seats to leave the roster without reusing their index, got %+v", seats)
+	}
+}
+
+// A connection the stopped room turned away leaves the seat disconnected,
+// so the reconnect window starts.
+func TestJoinStoppedRoom(t *testing.T) {
+	room := NewBattleManager().CreateRoom()
+	room.Reserve("alice", common.TeamRed)
+	room.Hub.Stop()
+
+	conn, _ := serverConn(t)
+	if err := room.Join(conn, ClientInfo{UserID: "alice"}); err != ErrStopped {
+		t.Fatalf("expected the stopped room to turn alice away, got %v", err)
+	}
+	room.mu.Lock()
+	conns := room.seats["alice"].conns
+	room.mu.Unlock()
+	if conns != 0 {
+		t.Errorf("expected alice's seat to have no connections, got %d", conns)
+	}
+}
+
+func TestReapIdleRooms(t *testing.T) {
+	m := NewBattleManager()
+	empty := m.CreateRoom()
+	abandoned := m.CreateRoom()
+	busy := m.CreateRoom()
+	now := time.Now()
+	abandoned.Hub.mu.Lock()
+	abandoned.Hub.joined = true // alice came and went
+	abandoned.Hub.idleSince = now
+	abandoned.Hub.mu.Unlock()
+	busy.Hub.mu.Lock()
+	busy.Hub.idleSince = time.Time{} // bob is still playing
+	busy.Hub.mu.Unlock()
+
+	m.reap(now.Add(DefaultIdleTimeout), DefaultIdleTimeout)
+	if _, ok := m.GetRoom(busy.ID); !ok {
+		t.Error("expected a room with a player connected to be kept")
+	}
+	for room, reason := range map[*Room]EndReason{empty: EndEmpty, abandoned: EndAbandoned} {
+		if _, ok := m.GetRoom(room.ID); ok {
+			t.Errorf("expected the %s room to be reaped", reason)
+		}
+		if got := room.Hub.EndReason(); got != reason {
+			t.Errorf("expected end reason %s, got %s", reason, got)
+		}
+	}
+}
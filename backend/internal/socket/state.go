@@ -0,0 +1,325 @@
+package socket
+
+import (
+	"log"
+	"time"
+
+	"cse-110-project-team-30/backend/internal/battle"
+	"cse-110-project-team-30/backend/internal/battle/common"
+	"cse-110-project-team-30/backend/internal/battle/troops"
+)
+
+// How often delta clients get a full snapshot, in updates, so they recover
+// from anything they missed without asking.
+const keyframeInterval = 25
+
+// Message kinds for state updates.
+const (
+	kindKeyframe = "keyframe"
+	kindDelta    = "delta"
+)
+
+// sharedState is the small, always-sent part of a state update.
+type sharedState struct {
+	Phase        Phase                         `json:"phase"`
+	PhaseEnds    int64                         `json:"phaseEnds,omitempty"` // Unix milliseconds when the phase times out or the countdown ends
+	Ready        map[string]bool               `json:"ready,omitempty"`     // players who confirmed, before the battle starts
+	EndReason    EndReason                     `json:"endReason,omitempty"` // why the room finished
+	Tick         int                           `json:"tick"`
+	ServerTime   int64                         `json:"serverTime"`   // Unix milliseconds when the tick was sent
+	TickDuration int64                         `json:"tickDuration"` // milliseconds of game time per tick
+	SendInterval int64                         `json:"sendInterval"` // milliseconds between updates
+	Ongoing      bool                          `json:"ongoing"`
+	TowerStatus  map[common.Team][]bool        `json:"towerStatus"`
+	DeployZones  map[common.Team][]common.Rect `json:"deployZones"`
+	Players      map[string]*battle.Player     `json:"players"`
+	Eliminated   map[common.Team]bool          `json:"eliminated"`
+	Winner       *common.Team                  `json:"winner"`
+	Events       []battle.Event                `json:"events"`
+	Stats        *battle.Stats                 `json:"stats,omitempty"`
+	Paused       *pauseState                   `json:"paused,omitempty"`
+	PauseLeft    map[common.Team]int64         `json:"pauseLeft"` // milliseconds of pause each team has left
+}
+
+// pauseState is a pause as clients see it.
+type pauseState struct {
+	Team   common.Team          `json:"team"`   // who asked for it
+	Until  int64                `json:"until"`  // Unix milliseconds when it ends on its own
+	Resume map[common.Team]bool `json:"resume"` // teams that want to carry on
+}
+
+// keyframe is the full state: every entity, including static towers.
+type keyframe struct {
+	Kind string `json:"kind"`
+	sharedState
+	Troops []troops.Entity `json:"troops"`
+}
+
+// delta is what changed since BaseTick, keyed by entity ID.
+type delta struct {
+	Kind string `json:"kind"`
+	sharedState
+	BaseTick int             `json:"baseTick"`
+	Spawned  []troops.Entity `json:"spawned"`
+	Moved    []entityMove    `json:"moved"`
+	Health   []entityHealth  `json:"health"`
+	Targets  []entityTarget  `json:"targets"`
+	Removed  []int           `json:"removed"`
+}
+
+// entityMove is sent when an entity moves or its velocity changes.
+type entityMove struct {
+	ID int     `json:"id"`
+	X  float64 `json:"x"`
+	Y  float64 `json:"y"`
+	VX float64 `json:"vx"`
+	VY float64 `json:"vy"`
+}
+
+type entityHealth struct {
+	ID     int `json:"id"`
+	Health int `json:"health"`
+}
+
+type entityTarget struct {
+	ID       int `json:"id"`
+	TargetID int `json:"targetID"`
+}
+
+// entitySnap is what the tracker remembers about an entity to diff against.
+type entitySnap struct {
+	Position common.Position
+	Velocity common.Position
+	Health   int
+	TargetID int
+}
+
+// deltaTracker remembers the entities sent last tick so the next update
+// only has to carry what changed.
+type deltaTracker struct {
+	tick     int
+	entities map[int]entitySnap
+}
+
+func newDeltaTracker() *deltaTracker {
+	return &deltaTracker{entities: make(map[int]entitySnap)}
+}
+
+// next diffs the entities against the previous tick and makes them the new
+// baseline.
+func (d *deltaTracker) next(tick int, entities []troops.Entity) delta {
+	out := delta{Kind: kindDelta, BaseTick: d.tick}
+	seen := make(map[int]entitySnap, len(entities))
+	for _, e := range entities {
+		t := e.GetTroop()
+		snap := entitySnap{Position: t.Position, Velocity: t.Velocity, Health: t.Health, TargetID: t.TargetID}
+		seen[t.ID] = snap
+
+		prev, ok := d.entities[t.ID]
+		switch {
+		case !ok:
+			out.Spawned = append(out.Spawned, e)
+		default:
+			if prev.Position != snap.Position || prev.Velocity != snap.Velocity {
+				out.Moved = append(out.Moved, entityMove{ID: t.ID, X: snap.Position.X, Y: snap.Position.Y, VX: snap.Velocity.X, VY: snap.Velocity.Y})
+			}
+			if prev.Health != snap.Health {
+				out.Health = append(out.Health, entityHealth{ID: t.ID, Health: snap.Health})
+			}
+			if prev.TargetID != snap.TargetID {
+				out.Targets = append(out.Targets, entityTarget{ID: t.ID, TargetID: snap.TargetID})
+			}
+		}
+	}
+	for id := range d.entities {
+		if _, ok := seen[id]; !ok {
+			out.Removed = append(out.Removed, id)
+		}
+	}
+	d.tick = tick
+	d.entities = seen
+	return out
+}
+
+// noTeam is the view of a client whose team is not known yet. Under fog it
+// only sees the towers.
+const noTeam common.Team = -1
+
+// viewKey groups the clients that are shown the same state.
+type viewKey struct {
+	fog       bool        // false: the whole battle
+	team      common.Team // whose vision, when fog is on
+	spectator bool        // the whole battle for spectators, who may be shown it late
+}
+
+// stateView is one tick's update as a view sees it, encoded at most once per
+// message kind and encoding.
+type stateView struct {
+	full    keyframe
+	diff    delta
+	encoded map[encodedKey][]byte
+}
+
+type encodedKey struct {
+	delta    bool
+	encoding Encoding
+}
+
+func (v *stateView) encode(delta bool, enc Encoding) []byte {
+	key := encodedKey{delta, enc}
+	if msg, ok := v.encoded[key]; ok {
+		return msg
+	}
+	var msg []byte
+	var err error
+	if delta {
+		msg, err = enc.Marshal(outbound{Type: msgState, Payload: v.diff})
+	} else {
+		msg, err = enc.Marshal(outbound{Type: msgState, Payload: v.full})
+	}
+	if err != nil {
+		log.Println("error marshaling state:", err)
+	}
+	v.encoded[key] = msg
+	return msg
+}
+
+// freeze encodes the view every way it can be sent, right away. The view
+// points into the live battle, so a view that is sent later, like the
+// spectators' delayed one, would otherwise show the battle as it is then.
+func (v *stateView) freeze() {
+	for _, enc := range []Encoding{EncodingJSON, EncodingMsgpack} {
+		v.encode(false, enc)
+		v.encode(true, enc)
+	}
+}
+
+// viewFor picks the view a client gets. Without fog everyone sees the whole
+// battle; with fog a client sees what their seat's team sees. Spectators
+// always see everything, on their own view so it can be delayed.
+func (h *Hub) viewFor(cl *client) viewKey {
+	if cl.Spectator {
+		return viewKey{spectator: true}
+	}
+	if !h.battle.HasFog() {
+		return viewKey{}
+	}
+	team := noTeam
+	if cl.Seat != nil {
+		team = cl.Seat.Team
+	}
+	if h.battle.VisionFor(team) == nil {
+		return viewKey{} // out of the game: watch everything
+	}
+	return viewKey{fog: true, team: team}
+}
+
+// visibleState narrows the state down to what a view may see.
+func (h *Hub) visibleState(key viewKey, shared sharedState) (sharedState, []troops.Entity) {
+	entities := h.battle.Troops
+	if key.fog {
+		vision := h.battle.VisionFor(key.team)
+		entities = vision.Visible(entities)
+		shared = hideFromTeam(shared, vision, entities)
+	}
+	return shared, entities
+}
+
+// newView builds the latest update for a view and advances its delta
+// tracker.
+func (h *Hub) newView(key viewKey, shared sharedState) *stateView {
+	shared, entities := h.visibleState(key, shared)
+
+	tracker, ok := h.deltas[key]
+	if !ok {
+		tracker = newDeltaTracker()
+		h.deltas[key] = tracker
+	}
+	diff := tracker.next(shared.Tick, entities)
+	diff.sharedState = shared
+	return &stateView{
+		full:    keyframe{Kind: kindKeyframe, sharedState: shared, Troops: entities},
+		diff:    diff,
+		encoded: make(map[encodedKey][]byte),
+	}
+}
+
+// hideFromTeam drops the events the team could not have seen and the other
+// teams' players, whose hands and resources are secret under fog.
+func hideFromTeam(shared sharedState, vision *battle.Vision, visible []troops.Entity) sharedState {
+	ids := make(map[int]bool, len(visible))
+	for _, e := range visible {
+		ids[e.GetTroop().ID] = true
+	}
+	events := []battle.Event{}
+	for _, e := range shared.Events {
+		if eventVisible(e, vision, ids) {
+			events = append(events, e)
+		}
+	}
+	players := make(map[string]*battle.Player)
+	for id, p := range shared.Players {
+		if p.Team == vision.Team {
+			players[id] = p
+		}
+	}
+	shared.Events = events
+	shared.Players = players
+	return shared
+}
+
+// eventVisible reports whether a team under fog learns about an event: its
+// own, team-wide ones like eliminations, and those involving an entity or a
+// tile it can see.
+func eventVisible(e battle.Event, vision *battle.Vision, ids map[int]bool) bool {
+	switch {
+	case e.Team == vision.Team:
+		return true
+	case e.EntityID == 0 && e.Position == nil:
+		return true
+	case ids[e.EntityID] && e.EntityID != 0, ids[e.TargetID] && e.TargetID != 0:
+		return true
+	case e.Position != nil:
+		return vision.CanSee(*e.Position)
+	}
+	return false
+}
+
+// keyframeFor is the full state as the client may see it right now, without
+// touching the delta trackers. Events stay queued for the next broadcast.
+func (h *Hub) keyframeFor(cl *client) keyframe {
+	shared, entities := h.visibleState(h.viewFor(cl), h.snapshot([]battle.Event{}))
+	return keyframe{Kind: kindKeyframe, sharedState: shared, Troops: entities}
+}
+
+// snapshot collects the shared part of the latest update.
+func (h *Hub) snapshot(events []battle.Event) sharedState {
+	state := sharedState{
+		Tick:         h.battle.TickCount,
+		ServerTime:   time.Now().UnixMilli(),
+		TickDuration: h.battle.TickDuration.Milliseconds(),
+		SendInterval: h.sendInterval.Milliseconds(),
+		Ongoing:      h.battle.Enabled,
+		TowerStatus:  h.battle.TowerStatus(),
+		DeployZones:  h.battle.DeployZones,
+		Players:      h.battle.Players,
+		Eliminated:   h.battle.Eliminated,
+		Winner:       h.battle.Winner,
+		Events:       events,
+	}
+	if !h.battle.Enabled {
+		state.Stats = h.stats
+	}
+	if p := h.battle.Paused; p != nil {
+		state.Paused = &pauseState{Team: p.Team, Until: p.Until.UnixMilli(), Resume: p.Resume}
+	}
+	state.Phase, state.PhaseEnds, state.Ready, state.EndReason = h.lobbyState()
+	if state.Phase == PhaseRunning || state.Phase == PhaseFinished {
+		state.Ready = nil
+	}
+	state.PauseLeft = make(map[common.Team]int64, len(h.battle.PauseLeft))
+	for team, left := range h.battle.PauseLeft {
+		state.PauseLeft[team] = left.Milliseconds()
+	}
+	return state
+}
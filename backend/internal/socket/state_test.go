@@ -0,0 +1,222 @@
+package socket
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"cse-110-project-team-30/backend/internal/battle"
+	"cse-110-project-team-30/backend/internal/battle/common"
+	"cse-110-project-team-30/backend/internal/battle/maps"
+	"cse-110-project-team-30/backend/internal/battle/troops"
+)
+
+func TestDeltaTracker(t *testing.T) {
+	b := battle.NewBattle()
+	tracker := newDeltaTracker()
+
+	first := tracker.next(1, b.Troops)
+	if len(first.Spawned) != len(b.Troops) {
+		t.Fatalf("expected every entity to be new on the first delta, got %d of %d", len(first.Spawned), len(b.Troops))
+	}
+
+	if still := tracker.next(2, b.Troops); len(still.Spawned)+len(still.Moved)+len(still.Health)+len(still.Removed) != 0 {
+		t.Fatalf("expected an empty delta when nothing changed, got %+v", still)
+	}
+
+	troop, err := b.SpawnTroop(common.TeamRed, common.NewPosition(3, 3), "SwordsmanOne")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tower := b.Towers[0].Entity.GetTroop()
+	tower.Health -= 5
+	d := tracker.next(3, b.Troops)
+	if d.BaseTick != 2 {
+		t.Errorf("expected base tick 2, got %d", d.BaseTick)
+	}
+	if len(d.Spawned) != 1 || d.Spawned[0].GetTroop() != troop {
+		t.Errorf("expected the new troop to be spawned, got %+v", d.Spawned)
+	}
+	if len(d.Health) != 1 || d.Health[0].ID != tower.ID || d.Health[0].Health != tower.Health {
+		t.Errorf("expected the tower's health change, got %+v", d.Health)
+	}
+
+	troop.Position = common.NewPosition(4, 3)
+	removed := b.Troops[1].GetTroop().ID
+	remaining := append(append([]troops.Entity{}, b.Troops[:1]...), b.Troops[2:]...)
+	d = tracker.next(4, remaining)
+	if len(d.Moved) != 1 || d.Moved[0].ID != troop.ID || d.Moved[0].X != 4 {
+		t.Errorf("expected the troop to move, got %+v", d.Moved)
+	}
+	if len(d.Removed) != 1 || d.Removed[0] != removed {
+		t.Errorf("expected entity %d to be removed, got %+v", removed, d.Removed)
+	}
+}
+
+// Keyframes carry an events list, empty or not, whether they are broadcast
+// or sent on their own.
+func TestKeyframeEvents(t *testing.T) {
+	h := NewHub(battle.NewBattle())
+	broadcast := keyframe{Kind: kindKeyframe, sharedState: h.snapshot(h.takeEvents())}
+	for name, kf := range map[string]keyframe{"broadcast": broadcast, "resync": h.keyframeFor(&client{})} {
+		data, err := json.Marshal(kf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var msg struct {
+			Events json.RawMessage `json:"events"`
+		}
+		json.Unmarshal(data, &msg)
+		if string(msg.Events) != "[]" {
+			t.Errorf("expected the %s keyframe to have no events, got %s", name, msg.Events)
+		}
+	}
+}
+
+func TestFogViews(t *testing.T) {
+	def, err := maps.Get("fog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := battle.NewBattleFromMap(def)
+	h := NewHub(b)
+	b.AddPlayer("alice", common.TeamRed, nil)
+	b.AddPlayer("bob", common.TeamBlue, []string{"SwordsmanOne"})
+	hidden, err := b.SpawnTroopAs("bob", common.NewPosition(2, 30), "SwordsmanOne")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	alice := &client{ClientInfo: ClientInfo{UserID: "alice", Seat: &Seat{UserID: "alice", Team: common.TeamRed}}}
+	key := h.viewFor(alice)
+	if !key.fog || key.team != common.TeamRed {
+		t.Fatalf("expected alice to get red's view, got %+v", key)
+	}
+	view := h.newView(key, h.snapshot(h.takeEvents()))
+	for _, e := range view.full.Troops {
+		if e.GetTroop() == hidden {
+			t.Error("alice should not see bob's troop")
+		}
+	}
+	if _, ok := view.full.Players["bob"]; ok {
+		t.Error("alice should not see bob's hand and resources")
+	}
+	for _, e := range view.full.Events {
+		if e.EntityID == hidden.ID {
+			t.Errorf("alice should not hear about bob's spawn, got %+v", e)
+		}
+	}
+
+	if got := h.viewFor(&client{ClientInfo: ClientInfo{UserID: "carol"}}); got.team != noTeam {
+		t.Errorf("expected a client without a team to see only towers, got %+v", got)
+	}
+}
+
+func TestSpectatorDelay(t *testing.T) {
+	def, err := maps.Get("fog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := battle.NewBattleFromMap(def)
+	h := NewHub(b)
+	b.AddPlayer("bob", common.TeamBlue, []string{"SwordsmanOne"})
+	hidden, err := b.SpawnTroopAs("bob", common.NewPosition(2, 30), "SwordsmanOne")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Spectators see through the fog
+	key := h.viewFor(&client{ClientInfo: ClientInfo{UserID: "carol", Spectator: true}})
+	if key != (viewKey{spectator: true}) {
+		t.Fatalf("expected the spectator view, got %+v", key)
+	}
+	found := false
+	for _, e := range h.keyframeFor(&client{ClientInfo: ClientInfo{Spectator: true}}).Troops {
+		found = found || e.GetTroop() == hidden
+	}
+	if !found {
+		t.Error("expected spectators to see bob's troop")
+	}
+
+	// Spectators are shown the battle as it was, not just an old tick number
+	type seen struct {
+		Position common.Position
+		Health   int
+	}
+	history := map[int]seen{}
+	h.SetSpectatorDelay(3 * h.sendInterval) // three updates
+	for i := 0; i < 6; i++ {
+		hidden.Health--
+		b.Tick()
+		history[b.TickCount] = seen{hidden.Position, hidden.Health}
+		view := h.delayForSpectators(h.newView(key, h.snapshot(h.takeEvents())))
+		switch {
+		case i < 3 && view != nil:
+			t.Errorf("tick %d: expected nothing before the delay passed, got tick %d", b.TickCount, view.full.Tick)
+		case i >= 3 && (view == nil || view.full.Tick != b.TickCount-3):
+			t.Errorf("tick %d: expected the view from 3 ticks ago, got %+v", b.TickCount, view)
+		case i >= 3:
+			var msg struct {
+				Payload struct {
+					Troops []struct {
+						ID int
+						seen
+					} `json:"troops"`
+				} `json:"payload"`
+			}
+			if err := json.Unmarshal(view.encode(false, EncodingJSON), &msg); err != nil {
+				t.Fatal(err)
+			}
+			for _, troop := range msg.Payload.Troops {
+				if want := history[view.full.Tick]; troop.ID == hidden.ID && troop.seen != want {
+					t.Errorf("tick %d: expected bob's troop as it was at tick %d, %+v, got %+v", b.TickCount, view.full.Tick, want, troop.seen)
+				}
+			}
+		}
+	}
+}
+
+func TestSetRates(t *testing.T) {
+	b := battle.NewBattle()
+	h := NewHub(b)
+	if err := h.SetRates(0, DefaultSendInterval); err == nil {
+		t.Error("expected a zero tick rate to be rejected")
+	}
+
+	go h.Run()
+	defer h.Stop()
+	for i := 0; i < 2; i++ { // the second call waits for the first to apply
+		if err := h.SetRates(50*time.Millisecond, 100*time.Millisecond); err != nil {
+			t.Fatal(err)
+		}
+	}
+	h.mu.Lock() // the hub applies rates with the lock held
+	tick, send := b.TickDuration, h.sendInterval
+	h.mu.Unlock()
+	if tick != 50*time.Millisecond || send != 100*time.Millisecond {
+		t.Errorf("expected 50ms ticks and 100ms updates, got %v and %v", tick, send)
+	}
+
+	stopped := NewHub(battle.NewBattle())
+	stopped.Stop()
+	if err := stopped.SetRates(50*time.Millisecond, 100*time.Millisecond); err == nil {
+		t.Error("expected setting the rates of a stopped room to fail")
+	}
+
+	// Matchmaking's rooms get the manager's rates
+	m := NewBattleManager()
+	if err := m.SetRates(0, time.Second); err == nil {
+		t.Error("expected the manager to reject a zero tick rate")
+	}
+	if err := m.SetRates(50*time.Millisecond, 100*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	room := m.CreateRoom()
+	defer room.Hub.Stop()
+	room.Hub.mu.Lock()
+	tick, send = room.Battle.TickDuration, room.Hub.sendInterval
+	room.Hub.mu.Unlock()
+	if tick != 50*time.Millisecond || send != 100*time.Millisecond {
+		t.Errorf("expected new rooms to tick at 50ms and send at 100ms, got %v and %v", tick, send)
+	}
+}
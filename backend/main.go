@@ -1,10 +1,15 @@
 package main
 
 import (
+	"cse-110-project-team-30/backend/internal/battle"
+	"cse-110-project-team-30/backend/internal/battle/maps"
 	"cse-110-project-team-30/backend/internal/socket"
 	"cse-110-project-team-30/backend/routes"
 	"fmt"
+	"log"
 	"net/http"
+	"os"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -28,9 +33,54 @@ func main() {
 	mux := http.NewServeMux()
 	routes.RegisterHelloWorld(mux)
 	godotenv.Load(".env")
+	if dir := os.Getenv("MAPS_DIR"); dir != "" {
+		names, err := maps.LoadDir(dir)
+		if err != nil {
+			log.Fatal("failed to load maps: ", err)
+		}
+		fmt.Printf("Loaded maps from %s: %v\n", dir, names)
+	}
 	mgr := socket.NewBattleManager()
+	idleTimeout := socket.DefaultIdleTimeout
+	if s := os.Getenv("ROOM_IDLE_TIMEOUT"); s != "" {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			log.Fatal("invalid ROOM_IDLE_TIMEOUT: ", err)
+		}
+		idleTimeout = d
+	}
+	mgr.StartReaper(idleTimeout)
+	if tick, send := os.Getenv("TICK_INTERVAL"), os.Getenv("SEND_INTERVAL"); tick != "" || send != "" {
+		tickInterval, sendInterval := battle.DefaultTickDuration, socket.DefaultSendInterval
+		var err error
+		if tick != "" {
+			if tickInterval, err = time.ParseDuration(tick); err != nil {
+				log.Fatal("invalid TICK_INTERVAL: ", err)
+			}
+		}
+		if send != "" {
+			if sendInterval, err = time.ParseDuration(send); err != nil {
+				log.Fatal("invalid SEND_INTERVAL: ", err)
+			}
+		}
+		if err := mgr.SetRates(tickInterval, sendInterval); err != nil {
+			log.Fatal("invalid room rates: ", err)
+		}
+	}
 	routes.RegisterBattleSocket(mux, mgr)
 	routes.RegisterNewGameWS(mux, mgr)
+
+	// Counters such as socket_violations stay off the public listener
+	metricsAddr := os.Getenv("METRICS_ADDR")
+	if metricsAddr == "" {
+		metricsAddr = "localhost:8081"
+	}
+	metrics := http.NewServeMux()
+	metrics.Handle("/debug/vars", socket.MetricsHandler())
+	go func() {
+		log.Println("metrics listener stopped:", http.ListenAndServe(metricsAddr, metrics))
+	}()
+
 	fmt.Print("Starting server on :8080\n")
 	http.ListenAndServe(":8080", withCORS(mux))
 }
@@ -7,12 +7,19 @@ import (
 	"os"
 	"strings"
 
+	"cse-110-project-team-30/backend/internal/battle"
 	"cse-110-project-team-30/backend/internal/socket"
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/gorilla/websocket"
 )
 
+// Roles a connection can take in a battle room.
+const (
+	rolePlayer    = "player"
+	roleSpectator = "spectator"
+)
+
 var upgrader = websocket.Upgrader{
 	CheckOrigin: func(r *http.Request) bool { return true },
 }
@@ -33,8 +40,11 @@ func RegisterBattleSocket(mux *http.ServeMux, bm *socket.BattleManager) {
 		}
 
 		// --- Wait for first message for authentication ---
+		conn.SetReadLimit(socket.MaxMessageSize)
+		socket.DefaultHeartbeat.Alive(conn)
 		_, msgBytes, err := conn.ReadMessage()
 		if err != nil {
+			socket.NoteReadError("", err)
 			conn.Close()
 			return
 		}
@@ -68,11 +78,79 @@ func RegisterBattleSocket(mux *http.ServeMux, bm *socket.BattleManager) {
 			return
 		}
 
-		userID, _ := claims["id"].(string)
+		userID, err := loginUser(claims)
+		if err != nil {
+			fmt.Printf("Connection turned away from room %s: %v\n", roomID, err)
+			conn.Close()
+			return
+		}
+
+		encoding, err := socket.ParseEncoding(authMsg.Encoding)
+		if err != nil {
+			conn.Close()
+			return
+		}
+
 		username, _ := claims["username"].(string)
+
+		switch authMsg.Role {
+		case "", rolePlayer:
+		case roleSpectator:
+			// Anyone signed in can watch, except the players themselves,
+			// who would see through the fog
+			if _, seated := room.Seat(userID); seated {
+				fmt.Printf("User %s turned away from room %s: players can't spectate their own match\n", userID, roomID)
+				conn.Close()
+				return
+			}
+			fmt.Printf("User %s (%s) spectating room %s\n", username, userID, roomID)
+			err := hub.AddClient(conn, socket.ClientInfo{
+				UserID:    userID,
+				Username:  username,
+				Deltas:    authMsg.Deltas,
+				Encoding:  encoding,
+				Spectator: true,
+			})
+			if err != nil {
+				fmt.Printf("User %s turned away from room %s: %v\n", userID, roomID, err)
+			}
+			return
+		default:
+			conn.Close()
+			return
+		}
+
+		// Only players matchmaking seated here get in, and the seat, not
+		// anything the client says, decides their team. Players who already
+		// joined can reconnect without a ticket for a while.
+		if err := battle.CheckDeck(authMsg.Deck); err != nil {
+			fmt.Printf("User %s turned away from room %s: %v\n", userID, roomID, err)
+			conn.Close()
+			return
+		}
+		var seat socket.Seat
+		if authMsg.Ticket != "" {
+			seat, err = checkTicket(authMsg.Ticket, userID, room)
+		} else {
+			seat, err = room.Resume(userID)
+		}
+		if err != nil {
+			fmt.Printf("User %s turned away from room %s: %v\n", userID, roomID, err)
+			conn.Close()
+			return
+		}
 		fmt.Printf("User %s (%s) connected to room %s\n", username, userID, roomID)
 
-		// Attach user info if your Hub supports it
-		hub.AddClient(conn /* optionally pass user info */)
+		err = room.Join(conn, socket.ClientInfo{
+			UserID:   userID,
+			Username: username,
+			Deck:     authMsg.Deck,
+			Deltas:   authMsg.Deltas,
+			Encoding: encoding,
+			Seat:     &seat,
+		})
+		if err != nil {
+			fmt.Printf("User %s turned away from room %s: %v\n", userID, roomID, err)
+		}
 	})
 }
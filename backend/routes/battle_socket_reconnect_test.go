@@ -0,0 +1,91 @@
+package routes
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"cse-110-project-team-30/backend/internal/socket"
+
+	"github.com/gorilla/websocket"
+)
+
+// readJoined waits for the joined message and the keyframe right after it.
+func readJoined(t *testing.T, ws *websocket.Conn) (resumed bool, hand []string) {
+	t.Helper()
+	ws.SetReadDeadline(time.Now().Add(2 * time.Second))
+	var joined struct {
+		Type    string `json:"type"`
+		Payload struct {
+			Resumed bool `json:"resumed"`
+			Player  struct {
+				Hand []string `json:"hand"`
+			} `json:"player"`
+		} `json:"payload"`
+	}
+	if err := ws.ReadJSON(&joined); err != nil || joined.Type != "joined" {
+		t.Fatalf("expected a joined message, got %+v, %v", joined, err)
+	}
+	var state struct {
+		Type    string `json:"type"`
+		Payload struct {
+			Kind string `json:"kind"`
+		} `json:"payload"`
+	}
+	if err := ws.ReadJSON(&state); err != nil || state.Type != "state" || state.Payload.Kind != "keyframe" {
+		t.Fatalf("expected a keyframe right after joining, got %+v, %v", state, err)
+	}
+	return joined.Payload.Resumed, joined.Payload.Player.Hand
+}
+
+func TestBattleSocketReconnect(t *testing.T) {
+	mux := http.NewServeMux()
+	bm := socket.NewBattleManager()
+	roomID := createNewGame(bm).RoomID
+	room, _ := bm.GetRoom(roomID)
+	RegisterBattleSocket(mux, bm)
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	token := testToken(t, "alice", "alice")
+	first := dialWithAuth(ts, roomID, AuthMessage{Type: "auth", Token: token, Ticket: testTicket(t, roomID, "alice")}, t)
+	resumed, dealt := readJoined(t, first)
+	if resumed {
+		t.Error("the first connection should not count as a resume")
+	}
+	first.Close()
+	time.Sleep(100 * time.Millisecond)
+
+	// Back within the grace window: no ticket needed
+	again := dialWithAuth(ts, roomID, AuthMessage{Type: "auth", Token: token}, t)
+	defer again.Close()
+	resumed, hand := readJoined(t, again)
+	if !resumed || len(hand) == 0 || strings.Join(hand, ",") != strings.Join(dealt, ",") {
+		t.Errorf("expected alice's seat and hand back, got resumed=%v hand=%v", resumed, hand)
+	}
+
+	// Someone on the roster who never joined still needs a ticket
+	testTicket(t, roomID, "bob")
+	bob := dialWithAuth(ts, roomID, AuthMessage{Type: "auth", Token: testToken(t, "bob", "bob")}, t)
+	defer bob.Close()
+	bob.SetReadDeadline(time.Now().Add(time.Second))
+	if _, _, err := bob.ReadMessage(); err == nil {
+		t.Error("expected a first join without a ticket to be turned away")
+	}
+
+	// After the grace window the seat is gone
+	room.ReconnectGrace = 0
+	again.Close()
+	time.Sleep(100 * time.Millisecond)
+	late := dialWithAuth(ts, roomID, AuthMessage{Type: "auth", Token: token}, t)
+	defer late.Close()
+	late.SetReadDeadline(time.Now().Add(time.Second))
+	if _, msg, err := late.ReadMessage(); err == nil {
+		var m map[string]any
+		json.Unmarshal(msg, &m)
+		t.Errorf("expected a reconnect after the grace window to be turned away, got %v", m["type"])
+	}
+}
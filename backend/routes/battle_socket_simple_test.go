@@ -1,8 +1,11 @@
 package routes
 
 import (
+	"errors"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
@@ -55,3 +58,107 @@ func TestBattleSocketRoomCreation(t *testing.T) {
 		t.Fatal("Expected non-empty message from hub")
 	}
 }
+
+func TestBattleSocketRepliesToRequests(t *testing.T) {
+	mux := http.NewServeMux()
+	bm := socket.NewBattleManager()
+	roomID := createNewGame(bm).RoomID
+	RegisterBattleSocket(mux, bm)
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	ws := dialTestWS(ts, roomID, t)
+	defer ws.Close()
+
+	ws.WriteMessage(websocket.TextMessage, []byte(`{"type":"spawn","id":"1","payload":{"troopType":"SwordsmanOne","team":"red","x":2,"y":20}}`))
+	if r := readReply(t, ws, "1"); r.Type != "error" || r.Payload.Code != "enemyTerritory" {
+		t.Errorf("expected an enemyTerritory error, got %+v", r)
+	}
+
+	ws.WriteMessage(websocket.TextMessage, []byte(`{"type":"spawn","id":"2","payload":{"troopType":"SwordsmanOne","team":"red","x":2,"y":2}}`))
+	if r := readReply(t, ws, "2"); r.Type != "ack" || r.Payload.EntityID == 0 {
+		t.Errorf("expected an ack with the new troop's ID, got %+v", r)
+	}
+
+	ws.WriteMessage(websocket.TextMessage, []byte(`{"type":"dance","id":"3"}`))
+	if r := readReply(t, ws, "3"); r.Type != "error" || r.Payload.Code != "unknownType" {
+		t.Errorf("expected an unknownType error, got %+v", r)
+	}
+}
+
+func TestBattleSocketClosesOnHugeMessages(t *testing.T) {
+	mux := http.NewServeMux()
+	bm := socket.NewBattleManager()
+	roomID := createNewGame(bm).RoomID
+	RegisterBattleSocket(mux, bm)
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	ws := dialTestWS(ts, roomID, t)
+	defer ws.Close()
+
+	huge := `{"type":"spawn","id":"1","payload":{"troopType":"` + strings.Repeat("x", socket.MaxMessageSize) + `"}}`
+	ws.WriteMessage(websocket.TextMessage, []byte(huge))
+	ws.SetReadDeadline(time.Now().Add(2 * time.Second))
+	for {
+		_, _, err := ws.ReadMessage()
+		var netErr net.Error
+		if errors.As(err, &netErr) && netErr.Timeout() {
+			t.Fatal("expected the server to close the connection")
+		}
+		if err != nil {
+			break
+		}
+	}
+}
+
+func TestBattleSocketIgnoresClientTeam(t *testing.T) {
+	mux := http.NewServeMux()
+	bm := socket.NewBattleManager()
+	roomID := createNewGame(bm).RoomID
+	RegisterBattleSocket(mux, bm)
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	red := dialTestWSAs(ts, roomID, "alice", t)
+	defer red.Close()
+	// Wait for alice to be seated so bob gets the second seat
+	red.SetReadDeadline(time.Now().Add(time.Second))
+	if _, _, err := red.ReadMessage(); err != nil {
+		t.Fatalf("ReadMessage failed: %v", err)
+	}
+	blue := dialTestWSAs(ts, roomID, "bob", t)
+	defer blue.Close()
+
+	blue.WriteMessage(websocket.TextMessage, []byte(`{"type":"spawn","id":"1","payload":{"troopType":"SwordsmanOne","team":"red","x":2,"y":2}}`))
+	if r := readReply(t, blue, "1"); r.Type != "error" || r.Payload.Code != "enemyTerritory" {
+		t.Errorf("expected bob to be kept out of red's zone whatever team the message claims, got %+v", r)
+	}
+	blue.WriteMessage(websocket.TextMessage, []byte(`{"type":"spawn","id":"2","payload":{"troopType":"SwordsmanOne","team":"red","x":2,"y":20}}`))
+	if r := readReply(t, blue, "2"); r.Type != "ack" {
+		t.Errorf("expected bob to spawn on blue's side, got %+v", r)
+	}
+}
+
+func TestBattleSocketDealsTheChosenDeck(t *testing.T) {
+	mux := http.NewServeMux()
+	bm := socket.NewBattleManager()
+	roomID := createNewGame(bm).RoomID
+	RegisterBattleSocket(mux, bm)
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	deck := []string{"CavalryTwo", "ArcherThree"}
+	ws := dialWithAuth(ts, roomID, AuthMessage{Type: "auth", Token: testToken(t, "alice", "alice"), Ticket: testTicket(t, roomID, "alice"), Deck: deck}, t)
+	defer ws.Close()
+	if _, hand := readJoined(t, ws); strings.Join(hand, ",") != strings.Join(deck, ",") {
+		t.Errorf("expected alice to be dealt the deck they chose, got %v", hand)
+	}
+
+	bogus := dialWithAuth(ts, roomID, AuthMessage{Type: "auth", Token: testToken(t, "bob", "bob"), Ticket: testTicket(t, roomID, "bob"), Deck: []string{"Dragon"}}, t)
+	defer bogus.Close()
+	bogus.SetReadDeadline(time.Now().Add(time.Second))
+	if _, _, err := bogus.ReadMessage(); err == nil {
+		t.Error("expected a deck with an unknown card to be turned away")
+	}
+}
@@ -0,0 +1,56 @@
+package routes
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"cse-110-project-team-30/backend/internal/socket"
+)
+
+func TestBattleSocketSpectator(t *testing.T) {
+	mux := http.NewServeMux()
+	bm := socket.NewBattleManager()
+	roomID := createNewGame(bm).RoomID
+	room, _ := bm.GetRoom(roomID)
+	RegisterBattleSocket(mux, bm)
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	player := dialTestWS(ts, roomID, t)
+	defer player.Close()
+
+	ws := dialWithAuth(ts, roomID, AuthMessage{Type: "auth", Token: testToken(t, "carol", "carol"), Role: "spectator"}, t)
+	defer ws.Close()
+	ws.SetReadDeadline(time.Now().Add(2 * time.Second))
+	var joined struct {
+		Type    string `json:"type"`
+		Payload struct {
+			Seat      *socket.Seat `json:"seat"`
+			Spectator bool         `json:"spectator"`
+		} `json:"payload"`
+	}
+	if err := ws.ReadJSON(&joined); err != nil || joined.Type != "joined" || !joined.Payload.Spectator || joined.Payload.Seat != nil {
+		t.Fatalf("expected to join as a spectator without a seat, got %+v, %v", joined, err)
+	}
+
+	if err := ws.WriteJSON(map[string]any{"type": "spawn", "id": "1", "payload": map[string]any{"troopType": "SwordsmanOne", "x": 2, "y": 2}}); err != nil {
+		t.Fatal(err)
+	}
+	if r := readReply(t, ws, "1"); r.Type != "error" || r.Payload.Code != "spectator" {
+		t.Errorf("expected spectators to be unable to spawn, got %+v", r)
+	}
+	if n := room.Spectators(); n != 1 {
+		t.Errorf("expected 1 spectator, got %d", n)
+	}
+
+	// Players can't watch their own match from the other side of the fog
+	testTicket(t, roomID, "bob")
+	bob := dialWithAuth(ts, roomID, AuthMessage{Type: "auth", Token: testToken(t, "bob", "bob"), Role: "spectator"}, t)
+	defer bob.Close()
+	bob.SetReadDeadline(time.Now().Add(time.Second))
+	if _, _, err := bob.ReadMessage(); err == nil {
+		t.Error("expected a seated player to be turned away as a spectator")
+	}
+}
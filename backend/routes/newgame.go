@@ -6,7 +6,11 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"time"
 
+	"cse-110-project-team-30/backend/internal/battle/common"
+	"cse-110-project-team-30/backend/internal/battle/maps"
+	"cse-110-project-team-30/backend/internal/bot"
 	"cse-110-project-team-30/backend/internal/socket"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -14,18 +18,60 @@ import (
 )
 
 type AuthMessage struct {
-	Type  string `json:"type"`  // "auth"
-	Token string `json:"token"` // JWT
+	Type       string   `json:"type"`                 // "auth"
+	Token      string   `json:"token"`                // JWT
+	Map        string   `json:"map,omitempty"`        // optional map name for matchmaking
+	Mode       string   `json:"mode,omitempty"`       // "1v1" (default), "2v2", "ffa3", "ffa4" or "bot"
+	Difficulty string   `json:"difficulty,omitempty"` // bot level for empty seats: "easy", "normal" (default) or "hard"
+	Deck       []string `json:"deck,omitempty"`       // battle room only: cards the player chose to bring, every troop type if empty
+	Deltas     bool     `json:"deltas,omitempty"`     // battle room only: receive keyframes and deltas instead of full state
+	Encoding   string   `json:"encoding,omitempty"`   // battle room only: "json" (default) or "msgpack" for binary frames
+	Ticket     string   `json:"ticket,omitempty"`     // battle room only: ticket from the "matched" message, not needed to reconnect
+	Role       string   `json:"role,omitempty"`       // battle room only: "player" (default) or "spectator"
 }
 
+// matchMode describes how a matchmaking mode fills a room.
+type matchMode struct {
+	Teams    int    // number of teams in the battle
+	TeamSize int    // players on each team
+	Bots     int    // seats always filled by server-side bots
+	Map      string // map played unless a player picks one with as many teams
+}
+
+// seats is how many players, humans and bots, a room of this mode holds.
+func (m matchMode) seats() int {
+	return m.Teams * m.TeamSize
+}
+
+var matchModes = map[string]matchMode{
+	"1v1":  {Teams: 2, TeamSize: 1, Map: maps.DefaultName},
+	"2v2":  {Teams: 2, TeamSize: 2, Map: maps.DefaultName},
+	"ffa3": {Teams: 3, TeamSize: 1, Map: "ffa3"},
+	"ffa4": {Teams: 4, TeamSize: 1, Map: "ffa4"},
+	"bot":  {Teams: 2, TeamSize: 1, Bots: 1, Map: maps.DefaultName},
+}
+
+// queueLeave says a queued player's connection closed or went quiet.
+type queueLeave struct {
+	userID string
+	conn   *websocket.Conn
+}
+
+// botQueueTimeout is how long a player waits in a queue before the empty
+// seats of their match are filled with bots.
+const botQueueTimeout = 30 * time.Second
+
 type MatchMessage struct {
 	Type   string `json:"type"` // "matched"
 	RoomID string `json:"roomID"`
-	Team   string `json:"team"` // "red" or "blue"
+	Team   string `json:"team"`   // "red", "blue", "green" or "yellow"
+	Seat   int    `json:"seat"`   // the player's seat in the room; the server holds it for them
+	Ticket string `json:"ticket"` // signed, short-lived pass to join the room in this seat
 }
 
 func RegisterNewGameWS(mux *http.ServeMux, bm *socket.BattleManager) {
 	waitingQueue := make(chan *socket.PlayerConn, 100) // wrap conn with user info
+	leftQueue := make(chan queueLeave)
 
 	mux.HandleFunc("/newgamews", func(w http.ResponseWriter, r *http.Request) {
 		conn, err := upgrader.Upgrade(w, r, nil)
@@ -35,9 +81,12 @@ func RegisterNewGameWS(mux *http.ServeMux, bm *socket.BattleManager) {
 		}
 
 		// --- wait for first message for authentication ---
+		conn.SetReadLimit(socket.MaxMessageSize)
+		socket.DefaultHeartbeat.Alive(conn)
 		_, msgBytes, err := conn.ReadMessage()
 		if err != nil {
 			log.Println("ws read message error:", err)
+			socket.NoteReadError("", err)
 			conn.Close()
 			return
 		}
@@ -75,82 +124,209 @@ func RegisterNewGameWS(mux *http.ServeMux, bm *socket.BattleManager) {
 			return
 		}
 
-		userID, _ := claims["id"].(string)
+		userID, err := loginUser(claims)
+		if err != nil {
+			log.Println("invalid JWT token:", err)
+			conn.Close()
+			return
+		}
+
+		mode := authMsg.Mode
+		if mode == "" {
+			mode = "1v1"
+		}
+		if _, ok := matchModes[mode]; !ok {
+			log.Println("unknown matchmaking mode:", mode)
+			conn.Close()
+			return
+		}
+
 		username, _ := claims["username"].(string)
 		player := &socket.PlayerConn{
-			Conn:     conn,
-			UserID:   userID,
-			Username: username,
+			Conn:       conn,
+			UserID:     userID,
+			Username:   username,
+			Map:        authMsg.Map,
+			Mode:       mode,
+			QueuedAt:   time.Now(),
+			Difficulty: authMsg.Difficulty,
 		}
 
 		waitingQueue <- player
+
+		// Nothing else is read from a queued player, but reading keeps the
+		// heartbeat going and notices when they leave
+		socket.DefaultHeartbeat.KeepAlive(conn, nil)
+		go func() {
+			for {
+				if _, _, err := conn.ReadMessage(); err != nil {
+					socket.NoteReadError(userID, err)
+					leftQueue <- queueLeave{userID: userID, conn: conn}
+					return
+				}
+				socket.DefaultHeartbeat.Alive(conn)
+			}
+		}()
 	})
 
 	// --- matchmaking goroutine ---
 	go func() {
-		queue := []*socket.PlayerConn{}
+		queues := map[string][]*socket.PlayerConn{}
 		inQueue := map[string]*socket.PlayerConn{}
 		log.Println("At least the logging works...")
+		timeoutTicker := time.NewTicker(time.Second)
+		defer timeoutTicker.Stop()
 
 		for {
 			// Receive new waiting players
 			select {
 			case player := <-waitingQueue:
-				if inQueue[player.UserID] != nil {
+				if old := inQueue[player.UserID]; old != nil && old.Mode == player.Mode {
 					log.Println("kick player")
-					inQueue[player.UserID].Conn.Close()       // already in queue
-					inQueue[player.UserID].Conn = player.Conn // update conn
+					old.Conn.Close()       // already in queue
+					old.Conn = player.Conn // update conn
+					old.Map = player.Map
+					old.Difficulty = player.Difficulty
 				} else {
+					if old != nil {
+						log.Println("kick player from", old.Mode, "queue")
+						old.Conn.Close()
+						queues[old.Mode] = removeFromQueue(queues[old.Mode], old)
+					}
 					log.Println("add player")
-					queue = append(queue, player)
+					queues[player.Mode] = append(queues[player.Mode], player)
 					inQueue[player.UserID] = player
 				}
-			}
 
-			// Match players in pairs
-			i := 0
-			for i < len(queue)-1 {
-				p1 := queue[i]
-				p2 := queue[i+1]
+				queues[player.Mode] = matchPlayers(bm, queues[player.Mode], matchModes[player.Mode], inQueue)
 
-				// skip if same user
-				if p1.UserID == p2.UserID {
-					i++
-					continue
+			case left := <-leftQueue:
+				// Matched players' connections close too, but they are no
+				// longer in the queue; neither is a connection that was
+				// replaced by a newer one
+				if p := inQueue[left.userID]; p != nil && p.Conn == left.conn {
+					log.Println(left.userID, "left the", p.Mode, "queue")
+					delete(inQueue, left.userID)
+					queues[p.Mode] = removeFromQueue(queues[p.Mode], p)
 				}
 
-				room := bm.CreateRoom()
-				msg1 := MatchMessage{Type: "matched", RoomID: room.ID, Team: "red"}
-				msg2 := MatchMessage{Type: "matched", RoomID: room.ID, Team: "blue"}
-				data1, _ := json.Marshal(msg1)
-				data2, _ := json.Marshal(msg2)
-
-				// Try sending match message, skip disconnected players
-				if err := p1.Conn.WriteMessage(websocket.TextMessage, data1); err != nil {
-					log.Println("p1 disconnected, skipping:", err)
-					p1.Conn.Close()
-					queue = append(queue[:i], queue[i+1:]...) // remove p1
-					inQueue[p1.UserID] = nil
-					continue
-				}
-				if err := p2.Conn.WriteMessage(websocket.TextMessage, data2); err != nil {
-					log.Println("p2 disconnected, skipping:", err)
-					p2.Conn.Close()
-					queue = append(queue[:i+1], queue[i+2:]...) // remove p2
-					inQueue[p2.UserID] = nil
-					continue
+			case <-timeoutTicker.C:
+				// Nobody came: fill the longest-waiting players' match with bots
+				for mode, queue := range queues {
+					if len(queue) > 0 && time.Since(queue[0].QueuedAt) >= botQueueTimeout {
+						n := min(len(queue), matchModes[mode].seats())
+						log.Println("queue timeout in", mode, "- filling", matchModes[mode].seats()-n, "seats with bots")
+						startMatch(bm, queue[:n], matchModes[mode], inQueue)
+						queues[mode] = queue[n:]
+					}
 				}
-
-				// Close matchmaking WS (client will connect to /ws/{roomID} next)
-				log.Println("matched players:", p1.UserID, "vs", p2.UserID, "in room", room.ID)
-				p1.Conn.Close()
-				p2.Conn.Close()
-
-				// Remove both players from queue
-				queue = append(queue[:i], queue[i+2:]...)
-				inQueue[p1.UserID] = nil
-				inQueue[p2.UserID] = nil
 			}
 		}
 	}()
 }
+
+// matchPlayers starts a room for every full group at the front of the queue
+// and returns the players still waiting.
+func matchPlayers(bm *socket.BattleManager, queue []*socket.PlayerConn, mode matchMode, inQueue map[string]*socket.PlayerConn) []*socket.PlayerConn {
+	groupSize := mode.seats() - mode.Bots
+	for len(queue) >= groupSize {
+		startMatch(bm, queue[:groupSize], mode, inQueue)
+		queue = queue[groupSize:]
+	}
+	return queue
+}
+
+// startMatch creates a room for the group and tells each player where to go.
+// Seats go round the teams (red, blue, red, blue, ... in 2v2) so teammates are
+// never next to each other in the queue; seats left over after the humans are
+// played by bots, and so are the seats of players who could not be told about
+// the match.
+func startMatch(bm *socket.BattleManager, group []*socket.PlayerConn, mode matchMode, inQueue map[string]*socket.PlayerConn) {
+	room := createMatchRoom(bm, group, mode)
+	teams := room.Battle.Teams
+	ids := make([]string, 0, len(group))
+	var botSeats []int
+	for i, p := range group {
+		delete(inQueue, p.UserID)
+		if err := sendMatch(room, p, teams[i%len(teams)]); err != nil {
+			// The room waits for every seat on its roster, so give the
+			// seat to a bot; the rest of the group still plays
+			log.Println("could not seat", p.UserID, "so a bot takes their place:", err)
+			room.Release(p.UserID)
+			botSeats = append(botSeats, i)
+			continue
+		}
+		ids = append(ids, p.UserID)
+	}
+	for seat := len(group); seat < mode.seats(); seat++ {
+		botSeats = append(botSeats, seat)
+	}
+	for _, seat := range botSeats {
+		commander, err := bot.NewCommander(botDifficulty(group), time.Now().UnixNano()+int64(seat))
+		if err != nil {
+			log.Println("unknown bot difficulty, using normal:", err)
+			commander, _ = bot.NewCommander(bot.Normal, time.Now().UnixNano()+int64(seat))
+		}
+		id := fmt.Sprintf("bot-%d", seat)
+		room.Hub.AddBot(bot.New(id, teams[seat%len(teams)], commander))
+		ids = append(ids, id)
+	}
+	log.Println("matched players:", ids, "in room", room.ID)
+}
+
+// sendMatch reserves a player's seat on the team and sends them their
+// ticket, then closes their matchmaking connection; the client connects to
+// /ws/{roomID} next.
+func sendMatch(room *socket.Room, p *socket.PlayerConn, team common.Team) error {
+	defer p.Conn.Close()
+	seat := room.Reserve(p.UserID, team)
+	ticket, err := issueTicket(room.ID, seat)
+	if err != nil {
+		return fmt.Errorf("issuing ticket: %w", err)
+	}
+	msg := MatchMessage{Type: "matched", RoomID: room.ID, Team: seat.Team.String(), Seat: seat.Index, Ticket: ticket}
+	data, _ := json.Marshal(msg)
+	return p.Conn.WriteMessage(websocket.TextMessage, data)
+}
+
+// createMatchRoom creates the room for a matched group. The first map choice
+// in the group with the right number of teams wins, falling back to the
+// mode's map.
+func createMatchRoom(bm *socket.BattleManager, group []*socket.PlayerConn, mode matchMode) *socket.Room {
+	mapName := mode.Map
+	for _, p := range group {
+		if p.Map == "" {
+			continue
+		}
+		if def, err := maps.Get(p.Map); err == nil && len(def.Teams()) == mode.Teams {
+			mapName = p.Map
+			break
+		}
+		log.Println("ignoring map choice", p.Map, "for", p.UserID)
+	}
+	room, err := bm.CreateRoomWithMap(mapName)
+	if err != nil {
+		log.Println("failed to load map, using default:", err)
+		room = bm.CreateRoom()
+	}
+	return room
+}
+
+// botDifficulty is the first difficulty asked for in the group, or normal.
+func botDifficulty(group []*socket.PlayerConn) string {
+	for _, p := range group {
+		if p.Difficulty != "" {
+			return p.Difficulty
+		}
+	}
+	return bot.Normal
+}
+
+func removeFromQueue(queue []*socket.PlayerConn, player *socket.PlayerConn) []*socket.PlayerConn {
+	for i, p := range queue {
+		if p == player {
+			return append(queue[:i], queue[i+1:]...)
+		}
+	}
+	return queue
+}
@@ -0,0 +1,75 @@
+package routes
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"cse-110-project-team-30/backend/internal/socket"
+
+	"github.com/gorilla/websocket"
+)
+
+// A player who can't be told about their match gives their seat to a bot,
+// so the room doesn't wait for them.
+func TestStartMatchSkipsUnreachablePlayers(t *testing.T) {
+	testToken(t, "alice", "alice") // sets JWT_SECRET
+	bm := socket.NewBattleManager()
+
+	var upgrader websocket.Upgrader
+	conns := make(chan *websocket.Conn, 2)
+	queueServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if conn, err := upgrader.Upgrade(w, r, nil); err == nil {
+			conns <- conn
+		}
+	}))
+	defer queueServer.Close()
+	queued := func(userID string) (*socket.PlayerConn, *websocket.Conn) {
+		client, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(queueServer.URL, "http"), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &socket.PlayerConn{Conn: <-conns, UserID: userID, Mode: "1v1"}, client
+	}
+	alice, aliceClient := queued("alice")
+	defer aliceClient.Close()
+	bob, bobClient := queued("bob")
+	defer bobClient.Close()
+	bob.Conn.Close() // the matched message can't reach bob
+
+	inQueue := map[string]*socket.PlayerConn{"alice": alice, "bob": bob}
+	startMatch(bm, []*socket.PlayerConn{bob, alice}, matchModes["1v1"], inQueue)
+
+	var matched MatchMessage
+	aliceClient.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if err := aliceClient.ReadJSON(&matched); err != nil || matched.Type != "matched" {
+		t.Fatalf("expected alice to be matched, got %+v, %v", matched, err)
+	}
+	room, ok := bm.GetRoom(matched.RoomID)
+	if !ok {
+		t.Fatal("expected the match's room to exist")
+	}
+	if seats := room.Seats(); len(seats) != 1 || seats[0].UserID != "alice" {
+		t.Fatalf("expected only alice on the roster, got %+v", seats)
+	}
+	if len(inQueue) != 0 {
+		t.Errorf("expected both players out of the queue, got %v", inQueue)
+	}
+
+	// Once alice connects, everyone on the roster is there
+	mux := http.NewServeMux()
+	RegisterBattleSocket(mux, bm)
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+	ws := dialWithAuth(ts, room.ID, AuthMessage{Type: "auth", Token: testToken(t, "alice", "alice"), Ticket: matched.Ticket}, t)
+	defer ws.Close()
+	deadline := time.Now().Add(2 * time.Second)
+	for room.Hub.Phase() == socket.PhaseWaiting {
+		if time.Now().After(deadline) {
+			t.Fatal("expected the room to stop waiting once alice joined")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -1,18 +1,134 @@
 package routes
 
 import (
+	"encoding/json"
+	"fmt"
 	"net/http/httptest"
+	"os"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"cse-110-project-team-30/backend/internal/socket"
+
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/gorilla/websocket"
 )
 
-// helper to make websocket connections
+const testJWTSecret = "test-secret"
+
+type newGameResponse struct {
+	RoomID string `json:"roomID"`
+}
+
+// rooms created by tests, so dialing can seat users the way matchmaking does
+var (
+	testRoomsMu sync.Mutex
+	testRooms   = map[string]*socket.Room{}
+)
+
+// testDeck is what every player in a test room draws from, so tests know
+// which cards are in hand
+var testDeck = []string{"SwordsmanOne", "ArcherOne", "SpearmanOne", "CavalryOne"}
+
+// helper to create a room the same way matchmaking does, minus the
+// countdown and the shuffle
+func createNewGame(bm *socket.BattleManager) newGameResponse {
+	room := bm.CreateRoom()
+	lobby := socket.DefaultLobby
+	lobby.Countdown = 0
+	room.Hub.SetLobby(lobby)
+	room.Hub.SetDecks(func() []string { return testDeck })
+	testRoomsMu.Lock()
+	testRooms[room.ID] = room
+	testRoomsMu.Unlock()
+	return newGameResponse{RoomID: room.ID}
+}
+
+// helper to seat a user round the teams and issue their match ticket
+func testTicket(t *testing.T, roomID, userID string) string {
+	testRoomsMu.Lock()
+	room := testRooms[roomID]
+	testRoomsMu.Unlock()
+	if room == nil {
+		t.Fatalf("room %s was not created with createNewGame", roomID)
+	}
+	teams := room.Battle.Teams
+	seat := room.Reserve(userID, teams[len(room.Seats())%len(teams)])
+	ticket, err := issueTicket(roomID, seat)
+	if err != nil {
+		t.Fatalf("issuing test ticket failed: %v", err)
+	}
+	return ticket
+}
+
+// helper to sign a JWT the way the REST backend does
+func testToken(t *testing.T, userID, username string) string {
+	os.Setenv("JWT_SECRET", testJWTSecret)
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"id":       userID,
+		"username": username,
+	})
+	signed, err := token.SignedString([]byte(testJWTSecret))
+	if err != nil {
+		t.Fatalf("signing test token failed: %v", err)
+	}
+	return signed
+}
+
+// testReply is an ack or error from a battle room
+type testReply struct {
+	Type    string `json:"type"`
+	ID      string `json:"id"`
+	Payload struct {
+		Code     string `json:"code"`
+		EntityID int    `json:"entityID"`
+	} `json:"payload"`
+}
+
+// helper that skips state updates until the reply to a request arrives
+func readReply(t *testing.T, ws *websocket.Conn, id string) testReply {
+	t.Helper()
+	ws.SetReadDeadline(time.Now().Add(2 * time.Second))
+	for {
+		var r testReply
+		if err := ws.ReadJSON(&r); err != nil {
+			t.Fatalf("ReadJSON failed: %v", err)
+		}
+		if r.Type != "state" && r.ID == id {
+			return r
+		}
+	}
+}
+
+var testUsers atomic.Int64
+
+// helper to make websocket connections, each as a new player
 func dialTestWS(ts *httptest.Server, roomID string, t *testing.T) *websocket.Conn {
+	return dialTestWSAs(ts, roomID, fmt.Sprintf("test-user-%d", testUsers.Add(1)), t)
+}
+
+// helper to connect to a room as a specific user who is ready to play
+func dialTestWSAs(ts *httptest.Server, roomID, userID string, t *testing.T) *websocket.Conn {
+	token := testToken(t, userID, "tester")
+	ws := dialWithAuth(ts, roomID, AuthMessage{Type: "auth", Token: token, Ticket: testTicket(t, roomID, userID)}, t)
+	if err := ws.WriteMessage(websocket.TextMessage, []byte(`{"type":"ready","id":"ready"}`)); err != nil {
+		t.Fatalf("ready failed: %v", err)
+	}
+	return ws
+}
+
+// helper to connect to a room with a hand-made auth message
+func dialWithAuth(ts *httptest.Server, roomID string, authMsg AuthMessage, t *testing.T) *websocket.Conn {
 	url := "ws" + ts.URL[len("http"):] + "/ws/" + roomID
 	ws, _, err := websocket.DefaultDialer.Dial(url, nil)
 	if err != nil {
 		t.Fatalf("WebSocket dial failed: %v", err)
 	}
+	auth, _ := json.Marshal(authMsg)
+	if err := ws.WriteMessage(websocket.TextMessage, auth); err != nil {
+		t.Fatalf("auth failed: %v", err)
+	}
 	return ws
 }
@@ -0,0 +1,99 @@
+package routes
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"cse-110-project-team-30/backend/internal/socket"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// matchTicketTTL is how long a player has to join their room after being
+// matched.
+const matchTicketTTL = time.Minute
+
+// ticketAudience keeps login tokens from passing as tickets and the other
+// way round.
+const ticketAudience = "battle-room"
+
+// ticketClaims bind a ticket to one user, room and seat. The user ID is the
+// subject.
+type ticketClaims struct {
+	RoomID string `json:"room"`
+	Team   string `json:"team"`
+	Seat   int    `json:"seat"`
+	jwt.RegisteredClaims
+}
+
+// issueTicket signs a short-lived ticket for a matched player's seat.
+func issueTicket(roomID string, seat socket.Seat) (string, error) {
+	secret := os.Getenv("JWT_SECRET")
+	if secret == "" {
+		return "", errors.New("JWT_SECRET not set")
+	}
+	now := time.Now()
+	claims := ticketClaims{
+		RoomID: roomID,
+		Team:   seat.Team.String(),
+		Seat:   seat.Index,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   seat.UserID,
+			Audience:  jwt.ClaimStrings{ticketAudience},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(matchTicketTTL)),
+		},
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secret))
+}
+
+// checkTicket verifies a ticket and that it was issued to the user for this
+// room, and returns the seat it holds in the room.
+func checkTicket(ticket, userID string, room *socket.Room) (socket.Seat, error) {
+	secret := os.Getenv("JWT_SECRET")
+	if secret == "" {
+		return socket.Seat{}, errors.New("JWT_SECRET not set")
+	}
+	var claims ticketClaims
+	_, err := jwt.ParseWithClaims(ticket, &claims, func(token *jwt.Token) (interface{}, error) {
+		return []byte(secret), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithAudience(ticketAudience), jwt.WithExpirationRequired())
+	if err != nil {
+		return socket.Seat{}, fmt.Errorf("invalid ticket: %w", err)
+	}
+	if claims.Subject != userID {
+		return socket.Seat{}, errors.New("ticket was issued to another user")
+	}
+	if claims.RoomID != room.ID {
+		return socket.Seat{}, errors.New("ticket is for another room")
+	}
+	seat, ok := room.Seat(userID)
+	if !ok {
+		return socket.Seat{}, errors.New("user is not on the room's roster")
+	}
+	if seat.Team.String() != claims.Team || seat.Index != claims.Seat {
+		return socket.Seat{}, errors.New("ticket does not match the user's seat")
+	}
+	return seat, nil
+}
+
+// loginUser returns the user ID a login token was issued for. Tickets are
+// signed with the same secret, so they are turned away here.
+func loginUser(claims jwt.MapClaims) (string, error) {
+	audience, err := claims.GetAudience()
+	if err != nil {
+		return "", fmt.Errorf("invalid token: %w", err)
+	}
+	for _, aud := range audience {
+		if aud == ticketAudience {
+			return "", errors.New("a ticket is not a login token")
+		}
+	}
+	userID, _ := claims["id"].(string)
+	if userID == "" {
+		return "", errors.New("token has no user ID")
+	}
+	return userID, nil
+}
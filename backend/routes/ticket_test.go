@@ -0,0 +1,107 @@
+package routes
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"cse-110-project-team-30/backend/internal/battle/common"
+	"cse-110-project-team-30/backend/internal/socket"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestCheckTicket(t *testing.T) {
+	testToken(t, "alice", "alice") // sets JWT_SECRET
+	bm := socket.NewBattleManager()
+	room := bm.CreateRoom()
+	other := bm.CreateRoom()
+	seat := room.Reserve("alice", common.TeamBlue)
+
+	ticket, err := issueTicket(room.ID, seat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := checkTicket(ticket, "alice", room); err != nil || got != seat {
+		t.Fatalf("expected alice's ticket to hold their seat, got %+v, %v", got, err)
+	}
+	if _, err := checkTicket(ticket, "mallory", room); err == nil {
+		t.Error("a ticket should only work for the user it was issued to")
+	}
+	if _, err := checkTicket(ticket, "alice", other); err == nil {
+		t.Error("a ticket should only work for its room")
+	}
+
+	offRoster, _ := issueTicket(room.ID, socket.Seat{UserID: "mallory", Team: common.TeamRed})
+	if _, err := checkTicket(offRoster, "mallory", room); err == nil {
+		t.Error("users off the roster should be turned away even with a signed ticket")
+	}
+
+	expired := jwt.NewWithClaims(jwt.SigningMethodHS256, ticketClaims{
+		RoomID: room.ID,
+		Team:   seat.Team.String(),
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   "alice",
+			Audience:  jwt.ClaimStrings{ticketAudience},
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(-time.Second)),
+		},
+	})
+	signed, _ := expired.SignedString([]byte(testJWTSecret))
+	if _, err := checkTicket(signed, "alice", room); err == nil {
+		t.Error("expired tickets should be rejected")
+	}
+
+	if _, err := checkTicket(testToken(t, "alice", "alice"), "alice", room); err == nil {
+		t.Error("a login token should not pass as a ticket")
+	}
+}
+
+func TestBattleSocketRequiresTicket(t *testing.T) {
+	mux := http.NewServeMux()
+	bm := socket.NewBattleManager()
+	roomID := createNewGame(bm).RoomID
+	RegisterBattleSocket(mux, bm)
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	ws := dialWithAuth(ts, roomID, AuthMessage{Type: "auth", Token: testToken(t, "mallory", "mallory")}, t)
+	defer ws.Close()
+
+	ws.SetReadDeadline(time.Now().Add(time.Second))
+	if _, _, err := ws.ReadMessage(); err == nil {
+		t.Fatal("expected the server to close a connection without a ticket")
+	}
+}
+
+func TestBattleSocketRefusesTicketAsLogin(t *testing.T) {
+	mux := http.NewServeMux()
+	bm := socket.NewBattleManager()
+	roomID := createNewGame(bm).RoomID
+	RegisterBattleSocket(mux, bm)
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	testToken(t, "alice", "alice") // sets JWT_SECRET
+	ticket := testTicket(t, roomID, "alice")
+	for _, role := range []string{rolePlayer, roleSpectator} {
+		ws := dialWithAuth(ts, roomID, AuthMessage{Type: "auth", Token: ticket, Ticket: ticket, Role: role}, t)
+		ws.SetReadDeadline(time.Now().Add(time.Second))
+		if _, _, err := ws.ReadMessage(); err == nil {
+			t.Errorf("expected a ticket used as a login token to be refused (%s)", role)
+		}
+		ws.Close()
+	}
+
+	noID := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"username": "nobody"})
+	signed, _ := noID.SignedString([]byte(testJWTSecret))
+	if _, err := loginUser(noID.Claims.(jwt.MapClaims)); err == nil {
+		t.Error("expected a token without a user ID to be refused")
+	}
+	ws := dialWithAuth(ts, roomID, AuthMessage{Type: "auth", Token: signed, Role: roleSpectator}, t)
+	defer ws.Close()
+	ws.SetReadDeadline(time.Now().Add(time.Second))
+	if _, _, err := ws.ReadMessage(); err == nil {
+		t.Error("expected a token without a user ID to be refused")
+	}
+}
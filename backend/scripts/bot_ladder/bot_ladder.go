@@ -0,0 +1,36 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"strings"
+
+	"cse-110-project-team-30/backend/internal/bot"
+)
+
+func main() {
+	mapName := flag.String("map", "classic", "two-team map to play on")
+	rounds := flag.Int("rounds", 4, "matches per pairing (sides swap every match)")
+	maxTicks := flag.Int("ticks", 3000, "ticks before a match is called")
+	seed := flag.Int64("seed", 1, "seed for the commanders' randomness")
+	names := flag.String("bots", strings.Join(bot.Commanders(), ","), "comma separated commanders to enter")
+	flag.Parse()
+
+	entrants := strings.Split(*names, ",")
+	ladder, err := bot.RunTournament(entrants, bot.TournamentOptions{
+		Map:      *mapName,
+		Rounds:   *rounds,
+		MaxTicks: *maxTicks,
+		Seed:     *seed,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Printf("Ladder on %s (%d rounds per pairing, %d ticks max):\n", *mapName, *rounds, *maxTicks)
+	fmt.Printf("%-4s %-16s %4s %4s %4s %6s\n", "#", "commander", "W", "L", "D", "points")
+	for i, s := range ladder {
+		fmt.Printf("%-4d %-16s %4d %4d %4d %6d\n", i+1, s.Name, s.Wins, s.Losses, s.Draws, s.Points())
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"io/ioutil"
 	"log"
 	"os"
+	"sort"
 	"text/template"
 )
 
@@ -15,7 +16,8 @@ type TroopStats struct {
 	HP        int     `json:"hp"`
 	Damage    int     `json:"damage"`
 	Level     int     `json:"level"`
-	Speed     float64 `json:"Speed"`
+	Speed     float64 `json:"Speed"`    // tiles per second
+	Cooldown  float64 `json:"Cooldown"` // seconds between attacks
 	Range     int     `json:"Range"`
 }
 
@@ -37,6 +39,7 @@ func New{{.Type}}(team common.Team, pos common.Position) Entity {
 			Health:   {{.HP}},
 			Damage:   {{.Damage}},
 			Speed:    {{.Speed}},
+			Cooldown: {{.Cooldown}},
 			Range:    {{.Range}},
 			Position: pos,
 			Team:     team,
@@ -76,7 +79,14 @@ import "cse-110-project-team-30/backend/internal/battle/common"
 // TroopRegistry maps string keys to constructor functions.
 var TroopRegistry = map[string]func(team common.Team, pos common.Position) Entity{
 {{- range . }}
-	"{{.}}": New{{.}},
+	"{{.Type}}": New{{.Type}},
+{{- end }}
+}
+
+// TroopLevels maps string keys to the troop's card level.
+var TroopLevels = map[string]int{
+{{- range . }}
+	"{{.Type}}": {{.Level}},
 {{- end }}
 }
 
@@ -88,6 +98,11 @@ func NewTroopByType(troopType string, team common.Team, pos common.Position) Ent
 	return nil
 }
 
+// TroopLevel returns the level of a troop type, or 0 if the type is unknown.
+func TroopLevel(troopType string) int {
+	return TroopLevels[troopType]
+}
+
 // AvailableTroopTypes returns all keys in the registry
 func AvailableTroopTypes() []string {
 	keys := make([]string, 0, len(TroopRegistry))
@@ -135,13 +150,20 @@ func main() {
 		log.Fatal(err)
 	}
 
-	// Keep track of keys for registry
-	keys := []string{}
+	// Keep track of keys and levels for registry
+	type registryEntry struct {
+		Type  string
+		Level int
+	}
+	entries := []registryEntry{}
 
 	// Generate troop files
 	for key, stats := range statsMap {
 		if stats.Speed == 0 {
-			stats.Speed = 1.0
+			stats.Speed = 5.0
+		}
+		if stats.Cooldown == 0 {
+			stats.Cooldown = 0.2
 		}
 		if stats.Range == 0 {
 			stats.Range = 1
@@ -154,17 +176,19 @@ func main() {
 		}
 
 		data := struct {
-			Type   string
-			HP     int
-			Damage int
-			Speed  float64
-			Range  int
+			Type     string
+			HP       int
+			Damage   int
+			Speed    float64
+			Cooldown float64
+			Range    int
 		}{
-			Type:   key,
-			HP:     stats.HP,
-			Damage: stats.Damage,
-			Speed:  stats.Speed,
-			Range:  stats.Range,
+			Type:     key,
+			HP:       stats.HP,
+			Damage:   stats.Damage,
+			Speed:    stats.Speed,
+			Cooldown: stats.Cooldown,
+			Range:    stats.Range,
 		}
 
 		if err := tmpl.Execute(f, data); err != nil {
@@ -173,8 +197,9 @@ func main() {
 		f.Close()
 		fmt.Println("Generated:", filename)
 
-		keys = append(keys, key)
+		entries = append(entries, registryEntry{Type: key, Level: stats.Level})
 	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Type < entries[j].Type })
 
 	// Generate TroopRegistry.go
 	regFile := fmt.Sprintf("%s/TroopRegistry.go", *outDir)
@@ -182,7 +207,7 @@ func main() {
 	if err != nil {
 		log.Fatal(err)
 	}
-	if err := regTmpl.Execute(f, keys); err != nil {
+	if err := regTmpl.Execute(f, entries); err != nil {
 		log.Fatal(err)
 	}
 	f.Close()
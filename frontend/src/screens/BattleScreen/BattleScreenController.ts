@@ -1,12 +1,13 @@
 import { ScreenController } from "../../types.ts";
-import type { ScreenSwitcher, WSResponse, Position } from "../../types.ts";
+import type { ScreenSwitcher, WSResponse, Position, ServerMessage, ErrorPayload, LatencyPayload, JoinedPayload, PlayerState, Rect } from "../../types.ts";
 import { BattleScreenModel } from "./BattleScreenModel.ts";
 import { BattleScreenView } from "./BattleScreenView.ts";
-import { BACKEND_URI, BATTLE_DURATION } from "../../constants.ts";
+import { BACKEND_URI, BATTLE_DURATION, MAX_RESOURCES } from "../../constants.ts";
+
+// Reconnecting to a battle room after a dropped connection
+const MAX_RECONNECT_ATTEMPTS = 10;
+const RECONNECT_DELAY_MS = 1000;
 
-/**
- * BattleScreenController - Coordinates battle logic between Model and View
- */
 function tempAlert(msg,duration)
 {
  var el = document.createElement("div");
@@ -15,6 +16,10 @@ function tempAlert(msg,duration)
  document.body.appendChild(el);
  return el;
 }
+
+/**
+ * BattleScreenController - Coordinates battle logic between Model and View
+ */
 export class BattleScreenController extends ScreenController {
   private model: BattleScreenModel;
   private view: BattleScreenView;
@@ -22,8 +27,13 @@ export class BattleScreenController extends ScreenController {
   private selectedCards: string[] = [];
   private battleTimer: number | null = null;
   private isMatchReady: boolean = false;
+  private battleWS: WebSocket | null = null;
   private callSpawnTroop?: (troop: string, x: number, y: number) => void;
   private alert: HTMLDivElement | null = null;
+  private nextRequestID: number = 1;
+  private rtt: number | null = null; // last round trip to the server, in ms
+  private userID: string | null = null; // whose entry in the state's players is ours
+  private team: number | null = null; // our team ID, from our seat
 
   constructor(screenSwitcher: ScreenSwitcher) {
     super();
@@ -95,6 +105,8 @@ export class BattleScreenController extends ScreenController {
         type: string;
         roomID: string;
         team: string;
+        seat: number;
+        ticket: string; // short-lived pass to join the room in our seat
       };
 
       if (msg.type === "matched") {
@@ -108,41 +120,7 @@ export class BattleScreenController extends ScreenController {
         this.startTimer();
 
         // Open the actual battle WebSocket
-        const ws = new WebSocket(`${BACKEND_URI}/ws/${msg.roomID}`);
-        const token = localStorage.getItem('jwt');
-        if (!token) {
-          console.error("No JWT found!");
-          ws.close();
-          return;
-        }
-        ws.onopen = () => {
-          ws.send(JSON.stringify({ type: "auth", token }));
-          // setup troop spawning callback
-          this.callSpawnTroop = (troop: string, x: number, y: number) => {
-            this.model.setTroopToPlace(null);
-            const position = this.model.isBlueTeam
-              ? { X: x, Y: y }
-              : this.flipBoardPosition({ X: x, Y: y });
-            ws.send(
-              JSON.stringify({
-                team: this.model.isBlueTeam ? "blue" : "red",
-                troopType: troop,
-                x: position.X,
-                y: position.Y,
-              }),
-            );
-          };
-          this.view.setCallSpawnTroop(this.callSpawnTroop!);
-        };
-
-        ws.onmessage = (event) => {
-          const data: WSResponse = this.marshalWSData(JSON.parse(event.data));
-          this.model.updateTiles(data.troops);
-          this.view.rerenderTroops(this.model.getTiles(), data.towerStatus);
-          if (data.ongoing === false) {
-            this.endBattle("complete");
-          }
-        };
+        this.connectBattle(msg.roomID, msg.ticket);
 
         // Close matchmaking WS after match
         matchWS.close();
@@ -150,6 +128,94 @@ export class BattleScreenController extends ScreenController {
     };
   }
 
+  /**
+   * Connect to the battle room. The ticket is only needed the first time;
+   * if the connection drops, reconnect with just the JWT and the server
+   * gives us our seat back.
+   */
+  private connectBattle(roomID: string, ticket?: string, attempt: number = 0): void {
+    const ws = new WebSocket(`${BACKEND_URI}/ws/${roomID}`);
+    const token = localStorage.getItem('jwt');
+    if (!token) {
+      console.error("No JWT found!");
+      ws.close();
+      return;
+    }
+    ws.onopen = () => {
+      // The server deals our hand from the cards we picked
+      ws.send(JSON.stringify({ type: "auth", token, ticket, deck: this.selectedCards }));
+      this.battleWS = ws;
+      // setup troop spawning callback
+      this.callSpawnTroop = (troop: string, x: number, y: number) => {
+        this.placeTroop(null);
+        const position = this.model.isBlueTeam
+          ? { X: x, Y: y }
+          : this.flipBoardPosition({ X: x, Y: y });
+        ws.send(
+          JSON.stringify({
+            type: "spawn",
+            id: String(this.nextRequestID++),
+            payload: {
+              troopType: troop,
+              x: position.X,
+              y: position.Y,
+            },
+          }),
+        );
+      };
+      this.view.setCallSpawnTroop(this.callSpawnTroop!);
+    };
+
+    ws.onmessage = (event) => {
+      const msg = JSON.parse(event.data) as ServerMessage;
+      if (msg.type === "joined") {
+        attempt = 0; // we're back in; start counting retries afresh
+        const joined = msg.payload as JoinedPayload;
+        this.userID = joined.seat?.userID ?? null;
+        this.team = joined.seat?.team ?? null;
+        if (joined.player) {
+          this.showPlayer(joined.player);
+        }
+        // The player already chose to play; confirm the ready check
+        ws.send(JSON.stringify({ type: "ready", id: String(this.nextRequestID++) }));
+        return;
+      }
+      if (msg.type === "latency") {
+        this.rtt = (msg.payload as LatencyPayload).rtt;
+        return;
+      }
+      if (msg.type === "error") {
+        // Tell the player why their placement was rejected
+        const el = tempAlert((msg.payload as ErrorPayload).message, 2000);
+        setTimeout(() => el.parentNode?.removeChild(el), 2000);
+        return;
+      }
+      if (msg.type !== "state") {
+        return;
+      }
+      const data: WSResponse = this.marshalWSData(msg.payload as WSResponse);
+      const me = this.userID ? data.players?.[this.userID] : undefined;
+      if (me) {
+        this.showPlayer(me);
+      }
+      if (this.team !== null && data.deployZones) {
+        this.showDeployZones(data.deployZones[this.team] ?? []);
+      }
+      this.model.updateTiles(data.troops);
+      this.view.rerenderTroops(this.model.getTiles(), data.towerStatus);
+      if (data.ongoing === false) {
+        this.endBattle("complete");
+      }
+    };
+
+    ws.onclose = () => {
+      // School networks drop connections; try to get our seat back
+      if (this.isMatchReady && attempt < MAX_RECONNECT_ATTEMPTS) {
+        setTimeout(() => this.connectBattle(roomID, undefined, attempt + 1), RECONNECT_DELAY_MS);
+      }
+    };
+  }
+
   /**
    * Start the battle
    */
@@ -190,7 +256,43 @@ export class BattleScreenController extends ScreenController {
   }
 
   /**
-   * Sets the cards the user selected
+   * Pick the troop to place next, or none, and show where it may go while
+   * one is picked
+   */
+  private placeTroop(troopType: string | null): void {
+    this.model.setTroopToPlace(troopType);
+    this.view.showDeployZones(troopType !== null);
+  }
+
+  /**
+   * Show our deploy zones, flipped like the troops when our side is drawn
+   * at the bottom
+   */
+  private showDeployZones(zones: Rect[]): void {
+    const drawn = this.model.isBlueTeam
+      ? zones
+      : zones.map((z) => ({ ...z, y: this.model.SIZE - z.y - z.h }));
+    if (this.model.setDeployZones(drawn)) {
+      this.view.renderDeployZones(drawn);
+    }
+  }
+
+  /**
+   * Show the hand and resources the server says we have. Playing a card
+   * draws the next one from our deck, so the hand changes as we play.
+   */
+  private showPlayer(player: PlayerState): void {
+    if (this.model.setPlayer(player)) {
+      this.view.renderCards(this.model.getHand(), (cardType) =>
+        this.handleCardClick(cardType),
+      );
+    }
+    this.view.updateResources(this.model.getResources(), MAX_RESOURCES);
+  }
+
+  /**
+   * Sets the cards the user selected. They are the deck the server deals
+   * our hand from, and are shown until it does.
    */
   public setCards(cards: string[]) {
     this.selectedCards = cards;
@@ -222,6 +324,10 @@ export class BattleScreenController extends ScreenController {
    */
   private handleLeaveClick(): void {
     console.log("entered leave click handler");
+    // Leaving a match in progress concedes it
+    if (this.isMatchReady && this.battleWS?.readyState === WebSocket.OPEN) {
+      this.battleWS.send(JSON.stringify({ type: "forfeit", id: String(this.nextRequestID++) }));
+    }
     this.endBattle("leave");
   }
 
@@ -237,6 +343,13 @@ export class BattleScreenController extends ScreenController {
       return;
     }
 
+    // The server only lets us play cards in our hand that we can pay for
+    if (!this.model.canPlay(cardType)) {
+      const el = tempAlert("Can't play " + cardType + " yet: it must be in your hand and paid for", 2000);
+      setTimeout(() => el.parentNode?.removeChild(el), 2000);
+      return;
+    }
+
     const problem = this.model.generateProblem(cardType);
     const operation = this.model.getCurrentCardObject()?.operation;
     if (!operation) {
@@ -292,7 +405,7 @@ export class BattleScreenController extends ScreenController {
 
     if (this.model.getCurrentStatus()) {
       console.log("correct!");
-      this.model.setTroopToPlace(this.model.getCurrentCardType());
+      this.placeTroop(this.model.getCurrentCardType());
     }
   }
 
@@ -331,4 +444,11 @@ export class BattleScreenController extends ScreenController {
   getView(): BattleScreenView {
     return this.view;
   }
+
+  /**
+   * Get the last measured round trip to the battle server in ms, if any
+   */
+  getRTT(): number | null {
+    return this.rtt;
+  }
 }
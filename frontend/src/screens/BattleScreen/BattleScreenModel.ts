@@ -1,7 +1,7 @@
 import troopsJson from "../../troops.json";
 import { generateMathProblem } from "../../mathGenerator";
 import { ARENA_SIZE } from "../../constants";
-import type { Troop, WSResponse, Grid } from "../../types";
+import type { Troop, WSResponse, Grid, PlayerState, Rect } from "../../types";
 
 /**
  * Defines the structure of a single troop/card
@@ -31,6 +31,9 @@ export class BattleScreenModel {
   private tiles!: Grid;
   private troopToPlace: string | null = null;
   public isBlueTeam: boolean = false;
+  private hand: string[] = []; // cards the server dealt us
+  private resources: number = 0;
+  private deployZones: Rect[] = []; // tiles we may place troops on, as drawn
 
   constructor() {
     // Initialize tiles grid
@@ -94,6 +97,40 @@ export class BattleScreenModel {
     return this.currentCardType;
   }
 
+  /**
+   * Take our hand and resources from the server. Returns whether the hand
+   * changed, so the cards only get redrawn when a card was played.
+   */
+  setPlayer(player: PlayerState): boolean {
+    this.resources = player.resources;
+    const hand = player.hand ?? [];
+    const changed = hand.join(",") !== this.hand.join(",");
+    this.hand = [...hand];
+    return changed;
+  }
+
+  /**
+   * Get the cards in our hand
+   */
+  getHand(): string[] {
+    return this.hand;
+  }
+
+  /**
+   * Get our resources
+   */
+  getResources(): number {
+    return this.resources;
+  }
+
+  /**
+   * Whether a card is in our hand and we have the resources to play it; a
+   * troop costs its level
+   */
+  canPlay(cardType: string): boolean {
+    return this.hand.includes(cardType) && this.resources >= troops[cardType].level;
+  }
+
   /**
    * Get the current card object
    */
@@ -155,6 +192,32 @@ export class BattleScreenModel {
     return this.troopToPlace;
   }
 
+  /**
+   * Set where we may place troops, in board coordinates as drawn. Returns
+   * whether the zones changed, e.g. because an enemy side tower fell.
+   */
+  setDeployZones(zones: Rect[]): boolean {
+    const changed = JSON.stringify(zones) !== JSON.stringify(this.deployZones);
+    this.deployZones = zones;
+    return changed;
+  }
+
+  /**
+   * Get where we may place troops
+   */
+  getDeployZones(): Rect[] {
+    return this.deployZones;
+  }
+
+  /**
+   * Whether we may place a troop on the tile
+   */
+  canDeployAt(x: number, y: number): boolean {
+    return this.deployZones.some(
+      (z) => x >= z.x && x < z.x + z.w && y >= z.y && y < z.y + z.h,
+    );
+  }
+
   /**
    * Reset battle state for a new game
    */
@@ -162,5 +225,8 @@ export class BattleScreenModel {
     this.currentProblem = null;
     this.currentCardType = null;
     this.currentIsCorrect = null;
+    this.hand = [];
+    this.resources = 0;
+    this.deployZones = [];
   }
 }
@@ -1,6 +1,6 @@
 import Konva from "konva";
 import { SpriteLookup, preloadSprites } from "./SpriteLookup.ts";
-import type { View, Grid } from "../../types.ts";
+import type { View, Grid, Rect } from "../../types.ts";
 import { STAGE_WIDTH, STAGE_HEIGHT, ARENA_SIZE } from "../../constants.ts";
 import type { BattleScreenModel } from "./BattleScreenModel.ts";
 
@@ -18,6 +18,10 @@ export class BattleScreenView implements View {
   private previewNode: Konva.Group | null = null;
   private previewTroopNode: Konva.Group | null = null;
   private timerText: Konva.Text;
+  private resourcesText: Konva.Text | null = null;
+  private deployZoneGroup: Konva.Group = new Konva.Group({ visible: false });
+  private tileWidth: number = 0;
+  private tileHeight: number = 0;
   private playerCrownText: Konva.Text;
   private enemyCrownText: Konva.Text;
   private readonly BATTLE_AREA_WIDTH: number = (STAGE_WIDTH / 3) * 2;
@@ -92,10 +96,13 @@ export class BattleScreenView implements View {
     this.group.add(bg);
   }
 
-  private renderCards(
+  renderCards(
     cardTypes: string[],
     onCardClick: (cardType: string) => void,
   ) {
+    // Replace whatever hand was shown before
+    this.cardsGroup.destroyChildren();
+
     const cols = 2;
     const rows = 2;
     const padding = 60;
@@ -209,8 +216,8 @@ export class BattleScreenView implements View {
       const tileX = Math.floor(localX / tileWidth);
       const tileY = Math.floor(localY / tileHeight);
 
-      if (tileY >= 16) {
-        // players side of the arena
+      if (this.model.canDeployAt(tileX, tileY)) {
+        // inside one of our deploy zones
         this.drawPreviewTroop(
           tileX,
           tileY,
@@ -315,6 +322,10 @@ export class BattleScreenView implements View {
         field.add(tile);
       }
     }
+    // Where we may place troops, shown while placing one
+    this.tileWidth = tileWidth;
+    this.tileHeight = tileHeight;
+    field.add(this.deployZoneGroup);
     this.battleFieldGroup.add(field);
     this.subscribeTileHover({
       group: this.battleFieldGroup,
@@ -1011,6 +1022,56 @@ export class BattleScreenView implements View {
   /**
    * Update timer display
    */
+  /**
+   * Draw the tiles we may place troops on
+   */
+  renderDeployZones(zones: Rect[]): void {
+    this.deployZoneGroup.destroyChildren();
+    for (const zone of zones) {
+      this.deployZoneGroup.add(
+        new Konva.Rect({
+          x: zone.x * this.tileWidth,
+          y: zone.y * this.tileHeight,
+          width: zone.w * this.tileWidth,
+          height: zone.h * this.tileHeight,
+          fill: "rgba(80, 200, 120, 0.25)",
+          stroke: "rgba(40, 140, 80, 0.8)",
+          strokeWidth: 2,
+          listening: false,
+        }),
+      );
+    }
+    this.group.getLayer()?.draw();
+  }
+
+  /**
+   * Show or hide the deploy zones, e.g. while a troop is being placed
+   */
+  showDeployZones(show: boolean): void {
+    this.deployZoneGroup.visible(show);
+    this.group.getLayer()?.draw();
+  }
+
+  /**
+   * Show how many resources we have to pay for cards
+   */
+  updateResources(resources: number, max: number): void {
+    if (!this.resourcesText) {
+      this.resourcesText = new Konva.Text({
+        x: 0,
+        y: 20,
+        width: this.CARD_AREA_WIDTH,
+        align: "center",
+        fontSize: 22,
+        fontFamily: "Arial",
+        fill: "black",
+      });
+      this.group.add(this.resourcesText);
+    }
+    this.resourcesText.text(`Resources: ${Math.floor(resources)} / ${max}`);
+    this.group.getLayer()?.draw();
+  }
+
   updateTimer(timeRemaining: number): void {
     const minutes = Math.floor(timeRemaining / 60);
     const seconds = timeRemaining % 60;
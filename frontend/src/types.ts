@@ -48,11 +48,94 @@ export interface ScreenSwitcher {
   switchToScreen(screen: Screen): void;
 }
 
+// Every battle room message after auth is wrapped in an envelope. Replies
+// to a request echo its id.
+export interface ServerMessage {
+  type: "state" | "joined" | "ack" | "error" | "latency";
+  id?: string;
+  payload: WSResponse | JoinedPayload | ErrorPayload | Record<string, unknown>;
+}
+
+export interface JoinedPayload {
+  seat: { userID: string; team: number; seat: number } | null;
+  resumed: boolean;
+  spectator?: boolean;
+  player?: PlayerState; // our hand and resources as the server dealt them
+}
+
+export interface LatencyPayload {
+  rtt: number; // milliseconds for the server's last heartbeat to come back
+}
+
+export interface ErrorPayload {
+  code: string; // e.g. "enemyTerritory", "notEnoughResources"
+  message: string;
+}
+
 export interface WSResponse {
+  phase?: "waiting" | "readyCheck" | "countdown" | "running" | "finished";
+  phaseEnds?: number; // Unix milliseconds when the phase times out or the countdown ends
+  ready?: Record<string, boolean>; // user ID → confirmed, before the battle starts
+  endReason?: "finished" | "cancelled" | "abandoned" | "empty"; // why the room finished
   tick: number;
+  serverTime?: number; // Unix milliseconds when the update was sent
+  tickDuration?: number; // milliseconds of game time per tick
+  sendInterval?: number; // milliseconds between updates
   troops: Troop[];
   ongoing: boolean;
   towerStatus: Record<number, boolean[]>; // team ID → [left, main, right]
+  deployZones?: Record<number, Rect[]>; // team ID → tiles the team may spawn on
+  players?: Record<string, PlayerState>; // user ID → player state
+  eliminated?: Record<number, boolean>; // team ID → knocked out
+  winner?: number | null; // last team standing, once the game is over
+  events?: BattleEvent[]; // what happened since the last update
+  paused?: PauseState; // set while the battle is paused
+  pauseLeft?: Record<number, number>; // team ID → milliseconds of pause left
+}
+
+export interface PauseState {
+  team: number; // who asked for the pause
+  until: number; // Unix milliseconds when it ends on its own
+  resume: Record<number, boolean>; // team ID → wants to carry on
+}
+
+export interface BattleEvent {
+  tick: number;
+  type:
+    | "spawn"
+    | "attack"
+    | "damage"
+    | "death"
+    | "towerDestroyed"
+    | "teamEliminated"
+    | "gameOver"
+    | "paused"
+    | "resumed"
+    | "forfeit";
+  entityID?: number;
+  targetID?: number;
+  towerID?: number;
+  team: number;
+  owner?: string;
+  troopType?: string;
+  amount?: number;
+  health?: number;
+  position?: Position;
+  winner?: number;
+}
+
+export interface PlayerState {
+  id: string;
+  team: number;
+  hand: string[];
+  resources: number;
+}
+
+export interface Rect {
+  x: number;
+  y: number;
+  w: number;
+  h: number;
 }
 
 export interface Troop {
@@ -60,10 +143,14 @@ export interface Troop {
   Type: string;
   Health: number;
   Team: number;
+  Owner?: string; // user ID of the player who spawned it
   Position: Position;
   Damage: number;
-  Speed: number;
+  Speed: number; // tiles per second
+  Cooldown?: number; // seconds between attacks
   Range: number;
+  Velocity?: Position; // tiles per second moved last tick
+  TargetID?: number; // entity attacked last tick, 0 if none
 }
 export interface Position {
   X: number;